@@ -1,49 +1,206 @@
 package config
 
 import (
-	"log"
+	"fmt"
 	"os"
+	"strconv"
+
+	"aws-iac-go/logging"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all infrastructure and application configuration.
 type Config struct {
-	AWSRegion              string
-	DynamoTableName        string
-	LambdaFuncName         string
-	LambdaRoleName         string
-	LambdaRoleARN          string // optional — if set, IAM creation is skipped entirely
-	PermissionsBoundaryARN string // required in accounts that enforce IAM boundaries
-	LogGroupName           string
-	SNSTopicName           string
-	AlertEmail             string // optional — email subscription is skipped if empty
-	ExternalAPIURL         string
-	MaxRetries             int
+	AWSRegion              string  `yaml:"aws_region"`
+	DynamoTableName        string  `yaml:"dynamo_table_name"`
+	LambdaFuncName         string  `yaml:"lambda_func_name"`
+	LambdaRoleName         string  `yaml:"lambda_role_name"`
+	LambdaRoleARN          string  `yaml:"lambda_role_arn"`          // optional — if set, IAM creation is skipped entirely
+	PermissionsBoundaryARN string  `yaml:"permissions_boundary_arn"` // required in accounts that enforce IAM boundaries
+	LogGroupName           string  `yaml:"log_group_name"`
+	SNSTopicName           string  `yaml:"sns_topic_name"`
+	AlertEmail             string  `yaml:"alert_email"` // optional — email subscription is skipped if empty
+	ExternalAPIURL         string  `yaml:"external_api_url"`
+	MaxRetries             int     `yaml:"max_retries"`
+
+	StateBackend  string `yaml:"state_backend"` // "local" (default), "s3", or "ssm"
+	StateFilePath string `yaml:"state_file_path"` // used when StateBackend == "local"
+	StateS3Bucket string `yaml:"state_s3_bucket"` // used when StateBackend == "s3"
+	StateS3Key    string `yaml:"state_s3_key"`    // used when StateBackend == "s3"
+	StateSSMParam string `yaml:"state_ssm_param"` // used when StateBackend == "ssm"
+
+	LambdaAliasName     string  `yaml:"lambda_alias_name"` // alias that always points at the live version, e.g. "live"
+	LambdaBakeMinutes   int     `yaml:"lambda_bake_minutes"` // how long to watch alarms before promoting a shifted deploy
+	LambdaCanaryPercent float64 `yaml:"lambda_canary_percent"`
+
+	DeploymentBucket string `yaml:"deployment_bucket"` // S3 bucket holding Lambda ZIP artifacts, created on demand
+
+	DynamoEndpoint     string `yaml:"dynamo_endpoint"`     // e.g. http://localhost:8000 — routes DynamoDB only to DynamoDB Local
+	LocalstackEndpoint string `yaml:"localstack_endpoint"` // e.g. http://localhost:4566 — routes every AWS service to LocalStack
+
+	DynamoTTLAttribute string `yaml:"dynamo_ttl_attribute"` // item attribute DynamoDB expires on, e.g. "expires_at"; TTL disabled if empty
+	DynamoTTLDays      int    `yaml:"dynamo_ttl_days"`      // item lifetime used to compute DynamoTTLAttribute on write
+
+	AlarmErrorThreshold         float64 `yaml:"alarm_error_threshold"`          // Lambda Errors (Sum per period) that trips the errors alarm
+	AlarmThrottleThreshold      float64 `yaml:"alarm_throttle_threshold"`       // Lambda Throttles (Sum per period) that trips the throttles alarm
+	AlarmDynamoThrottleThreshold float64 `yaml:"alarm_dynamo_throttle_threshold"` // DynamoDB ThrottledRequests (Sum per period) that trips the dynamo-throttles alarm
+	AlarmAnomalyBandWidth       float64 `yaml:"alarm_anomaly_band_width"`       // width (in standard deviations) of the ANOMALY_DETECTION_BAND for Duration/ProcessedRecords
 }
 
-// Load reads configuration from environment variables with sensible defaults.
+// Load reads configuration from a YAML file named by CONFIG_FILE, or from
+// environment variables with sensible defaults if CONFIG_FILE is unset.
+// Either way the result passes through Validate() before being returned.
 func Load() *Config {
-	cfg := &Config{
-		AWSRegion:              getEnv("AWS_REGION", "eu-west-1"),
-		DynamoTableName:        getEnv("DYNAMO_TABLE_NAME", "iac-data-store"),
-		LambdaFuncName:         getEnv("LAMBDA_FUNC_NAME", "iac-data-fetcher"),
-		LambdaRoleName:         getEnv("LAMBDA_ROLE_NAME", "iac-lambda-role"),
-		LambdaRoleARN:          getEnv("LAMBDA_ROLE_ARN", ""),          // e.g. arn:aws:iam::123456789:role/my-role
-		PermissionsBoundaryARN: getEnv("PERMISSIONS_BOUNDARY_ARN", ""), // e.g. arn:aws:iam::123456789:policy/MyBoundary
-		LogGroupName:           getEnv("LOG_GROUP_NAME", "/aws/lambda/iac-data-fetcher"),
-		SNSTopicName:           getEnv("SNS_TOPIC_NAME", "iac-alerts"),
-		AlertEmail:             getEnv("ALERT_EMAIL", ""),
-		ExternalAPIURL:         getEnv("EXTERNAL_API_URL", "https://jsonplaceholder.typicode.com/posts"),
-		MaxRetries:             3,
-	}
-
-	log.Printf("[config] Region: %s | Table: %s | Lambda: %s",
-		cfg.AWSRegion, cfg.DynamoTableName, cfg.LambdaFuncName)
+	// Load runs before main establishes the per-deployment logging.Entry, so
+	// it logs through a bare, component-tagged logger rather than logging.Step.
+	logger := logging.New().With("component", "config")
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		cfg, err := LoadFromFile(path)
+		if err != nil {
+			logger.Error("failed to load config file", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("loaded config file", "path", path, "region", cfg.AWSRegion,
+			"table", cfg.DynamoTableName, "lambda", cfg.LambdaFuncName)
+		return cfg
+	}
+
+	cfg := defaultConfig()
+	overlayEnv(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		logger.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("loaded config from environment", "region", cfg.AWSRegion,
+		"table", cfg.DynamoTableName, "lambda", cfg.LambdaFuncName)
 	return cfg
 }
 
+// defaultConfig returns the fallback values applied to any field left unset
+// by the caller, whether that's an unset environment variable (Load) or a
+// field omitted from a YAML file (LoadFromFile). Fields that default to the
+// zero value (e.g. LambdaRoleARN, AlertEmail) are left off since Go already
+// zero-initializes them.
+func defaultConfig() *Config {
+	return &Config{
+		AWSRegion:       "eu-west-1",
+		DynamoTableName: "iac-data-store",
+		LambdaFuncName:  "iac-data-fetcher",
+		LambdaRoleName:  "iac-lambda-role",
+		LogGroupName:    "/aws/lambda/iac-data-fetcher",
+		SNSTopicName:    "iac-alerts",
+		ExternalAPIURL:  "https://jsonplaceholder.typicode.com/posts",
+		MaxRetries:      3,
+
+		StateBackend:  "local",
+		StateFilePath: "iac-state.json",
+		StateS3Key:    "iac-state.json",
+
+		LambdaAliasName:     "live",
+		LambdaBakeMinutes:   5,
+		LambdaCanaryPercent: 10,
+
+		DynamoTTLDays: 30,
+
+		AlarmErrorThreshold:          1,
+		AlarmThrottleThreshold:       1,
+		AlarmDynamoThrottleThreshold: 1,
+		AlarmAnomalyBandWidth:        2,
+	}
+}
+
+// LoadFromFile parses a YAML config file into a Config, overlays any set
+// environment variables on top (env wins over the file, matching Load's
+// env-var precedence), validates the result, and returns it. This is what
+// lets operators commit named per-environment configs (dev.yaml, prod.yaml)
+// instead of juggling shell exports.
+func LoadFromFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	// Start from the same fallback values Load() uses for the env-var path,
+	// so a minimal YAML file doesn't have to restate every operational knob
+	// (max_retries, bake minutes, alarm thresholds, ...) just to pass Validate.
+	cfg := defaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config file: %w", err)
+	}
+
+	overlayEnv(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validate config: %w", err)
+	}
+	return cfg, nil
+}
+
+// overlayEnv applies any set environment variables on top of cfg (the
+// defaults, or a file-loaded Config), using each field's current value as
+// the fallback so an unset env var never clobbers what the file specified.
+func overlayEnv(cfg *Config) {
+	cfg.AWSRegion = getEnv("AWS_REGION", cfg.AWSRegion)
+	cfg.DynamoTableName = getEnv("DYNAMO_TABLE_NAME", cfg.DynamoTableName)
+	cfg.LambdaFuncName = getEnv("LAMBDA_FUNC_NAME", cfg.LambdaFuncName)
+	cfg.LambdaRoleName = getEnv("LAMBDA_ROLE_NAME", cfg.LambdaRoleName)
+	cfg.LambdaRoleARN = getEnv("LAMBDA_ROLE_ARN", cfg.LambdaRoleARN)
+	cfg.PermissionsBoundaryARN = getEnv("PERMISSIONS_BOUNDARY_ARN", cfg.PermissionsBoundaryARN)
+	cfg.LogGroupName = getEnv("LOG_GROUP_NAME", cfg.LogGroupName)
+	cfg.SNSTopicName = getEnv("SNS_TOPIC_NAME", cfg.SNSTopicName)
+	cfg.AlertEmail = getEnv("ALERT_EMAIL", cfg.AlertEmail)
+	cfg.ExternalAPIURL = getEnv("EXTERNAL_API_URL", cfg.ExternalAPIURL)
+	cfg.MaxRetries = getEnvInt("MAX_RETRIES", cfg.MaxRetries)
+
+	cfg.StateBackend = getEnv("STATE_BACKEND", cfg.StateBackend)
+	cfg.StateFilePath = getEnv("STATE_FILE_PATH", cfg.StateFilePath)
+	cfg.StateS3Bucket = getEnv("STATE_S3_BUCKET", cfg.StateS3Bucket)
+	cfg.StateS3Key = getEnv("STATE_S3_KEY", cfg.StateS3Key)
+	cfg.StateSSMParam = getEnv("STATE_SSM_PARAM", cfg.StateSSMParam)
+
+	cfg.LambdaAliasName = getEnv("LAMBDA_ALIAS_NAME", cfg.LambdaAliasName)
+	cfg.LambdaBakeMinutes = getEnvInt("LAMBDA_BAKE_MINUTES", cfg.LambdaBakeMinutes)
+	cfg.LambdaCanaryPercent = getEnvFloat("LAMBDA_CANARY_PERCENT", cfg.LambdaCanaryPercent)
+
+	cfg.DeploymentBucket = getEnv("DEPLOYMENT_BUCKET", cfg.DeploymentBucket)
+
+	cfg.DynamoEndpoint = getEnv("DYNAMO_ENDPOINT", cfg.DynamoEndpoint)
+	cfg.LocalstackEndpoint = getEnv("LOCALSTACK_ENDPOINT", cfg.LocalstackEndpoint)
+
+	cfg.DynamoTTLAttribute = getEnv("DYNAMO_TTL_ATTRIBUTE", cfg.DynamoTTLAttribute)
+	cfg.DynamoTTLDays = getEnvInt("DYNAMO_TTL_DAYS", cfg.DynamoTTLDays)
+
+	cfg.AlarmErrorThreshold = getEnvFloat("ALARM_ERROR_THRESHOLD", cfg.AlarmErrorThreshold)
+	cfg.AlarmThrottleThreshold = getEnvFloat("ALARM_THROTTLE_THRESHOLD", cfg.AlarmThrottleThreshold)
+	cfg.AlarmDynamoThrottleThreshold = getEnvFloat("ALARM_DYNAMO_THROTTLE_THRESHOLD", cfg.AlarmDynamoThrottleThreshold)
+	cfg.AlarmAnomalyBandWidth = getEnvFloat("ALARM_ANOMALY_BAND_WIDTH", cfg.AlarmAnomalyBandWidth)
+}
+
 func getEnv(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
 	}
 	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
 }
\ No newline at end of file
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+)
+
+var (
+	regionPattern = regexp.MustCompile(`^[a-z]{2}-[a-z]+-\d$`)
+	iamARNPattern = regexp.MustCompile(`^arn:aws:iam::\d{12}:(role|policy)/`)
+)
+
+// Validate catches configuration mistakes that would otherwise surface as a
+// confusing AWS API error partway through a deploy: a malformed region, an
+// IAM ARN missing its account ID, an alert email with no "@", a non-HTTPS
+// external API, or a retry count outside a sane range.
+func (c *Config) Validate() error {
+	if !regionPattern.MatchString(c.AWSRegion) {
+		return fmt.Errorf("invalid AWSRegion %q: must match %s", c.AWSRegion, regionPattern)
+	}
+
+	if c.LambdaRoleARN != "" && !iamARNPattern.MatchString(c.LambdaRoleARN) {
+		return fmt.Errorf("invalid LambdaRoleARN %q: must match %s", c.LambdaRoleARN, iamARNPattern)
+	}
+	if c.PermissionsBoundaryARN != "" && !iamARNPattern.MatchString(c.PermissionsBoundaryARN) {
+		return fmt.Errorf("invalid PermissionsBoundaryARN %q: must match %s", c.PermissionsBoundaryARN, iamARNPattern)
+	}
+
+	if c.AlertEmail != "" {
+		if _, err := mail.ParseAddress(c.AlertEmail); err != nil {
+			return fmt.Errorf("invalid AlertEmail %q: %w", c.AlertEmail, err)
+		}
+	}
+
+	u, err := url.Parse(c.ExternalAPIURL)
+	if err != nil {
+		return fmt.Errorf("invalid ExternalAPIURL %q: %w", c.ExternalAPIURL, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("invalid ExternalAPIURL %q: scheme must be https", c.ExternalAPIURL)
+	}
+
+	if c.MaxRetries < 1 || c.MaxRetries > 10 {
+		return fmt.Errorf("invalid MaxRetries %d: must be between 1 and 10", c.MaxRetries)
+	}
+
+	if c.LambdaCanaryPercent <= 0 || c.LambdaCanaryPercent > 100 {
+		return fmt.Errorf("invalid LambdaCanaryPercent %g: must be in (0, 100]", c.LambdaCanaryPercent)
+	}
+
+	return nil
+}
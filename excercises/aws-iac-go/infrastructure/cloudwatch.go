@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 
 	"aws-iac-go/config"
+	"aws-iac-go/logging"
+	"aws-iac-go/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
@@ -17,6 +18,8 @@ import (
 
 // SetupCloudWatch creates the log group, metric alarms, and dashboard for the Lambda function.
 func SetupCloudWatch(ctx context.Context, awsCfg aws.Config, cfg *config.Config, funcARN, snsARN string) error {
+	entry := logging.Step(ctx, "cloudwatch", cfg.LambdaFuncName)
+
 	if err := setupLogGroup(ctx, awsCfg, cfg); err != nil {
 		return err
 	}
@@ -25,7 +28,7 @@ func SetupCloudWatch(ctx context.Context, awsCfg aws.Config, cfg *config.Config,
 	}
 	if err := setupDashboard(ctx, awsCfg, cfg); err != nil {
 		// Dashboard is optional — do not abort the deployment
-		log.Printf("[cloudwatch] WARN: Could not create dashboard: %v", err)
+		entry.WithField("error", err).Warn("Could not create dashboard")
 	}
 	return nil
 }
@@ -34,9 +37,10 @@ func SetupCloudWatch(ctx context.Context, awsCfg aws.Config, cfg *config.Config,
 
 func setupLogGroup(ctx context.Context, awsCfg aws.Config, cfg *config.Config) error {
 	client := cloudwatchlogs.NewFromConfig(awsCfg)
+	entry := logging.Step(ctx, "cloudwatch", cfg.LogGroupName)
 
 	// CreateLogGroup is idempotent — safe to call if the group already exists
-	log.Printf("[cloudwatch] Creating log group: %s", cfg.LogGroupName)
+	entry.Infof("Creating log group: %s", cfg.LogGroupName)
 	_, err := client.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{
 		LogGroupName: aws.String(cfg.LogGroupName),
 		Tags: map[string]string{
@@ -54,10 +58,10 @@ func setupLogGroup(ctx context.Context, awsCfg aws.Config, cfg *config.Config) e
 		RetentionInDays: aws.Int32(30),
 	})
 	if err != nil {
-		log.Printf("[cloudwatch] WARN: Could not set log retention: %v", err)
+		entry.WithField("error", err).Warn("Could not set log retention")
 	}
 
-	log.Printf("[cloudwatch] Log group ready: %s (retention: 30 days)", cfg.LogGroupName)
+	entry.Infof("Log group ready: %s (retention: 30 days)", cfg.LogGroupName)
 	return nil
 }
 
@@ -65,6 +69,12 @@ func setupLogGroup(ctx context.Context, awsCfg aws.Config, cfg *config.Config) e
 
 func setupAlarms(ctx context.Context, awsCfg aws.Config, cfg *config.Config, snsARN string) error {
 	client := cloudwatch.NewFromConfig(awsCfg)
+	entry := logging.Step(ctx, "cloudwatch", cfg.LambdaFuncName)
+
+	errorsAlarm := fmt.Sprintf("%s-errors", cfg.LambdaFuncName)
+	durationAlarm := fmt.Sprintf("%s-duration", cfg.LambdaFuncName)
+	throttlesAlarm := fmt.Sprintf("%s-throttles", cfg.LambdaFuncName)
+	dynamoThrottlesAlarm := fmt.Sprintf("%s-dynamo-throttles", cfg.LambdaFuncName)
 
 	alarms := []struct {
 		name       string
@@ -76,31 +86,31 @@ func setupAlarms(ctx context.Context, awsCfg aws.Config, cfg *config.Config, sns
 		desc       string
 	}{
 		{
-			name:       fmt.Sprintf("%s-errors", cfg.LambdaFuncName),
+			name:       errorsAlarm,
 			metric:     "Errors",
-			threshold:  1,
+			threshold:  cfg.AlarmErrorThreshold,
 			period:     300, // 5 minutes
 			comparison: cwTypes.ComparisonOperatorGreaterThanOrEqualToThreshold,
 			stat:       "Sum",
 			desc:       "Alert when the Lambda function returns an error",
 		},
 		{
-			name:       fmt.Sprintf("%s-duration", cfg.LambdaFuncName),
-			metric:     "Duration",
-			threshold:  45000, // 45s = 75% of the 60s timeout
+			name:       throttlesAlarm,
+			metric:     "Throttles",
+			threshold:  cfg.AlarmThrottleThreshold,
 			period:     300,
-			comparison: cwTypes.ComparisonOperatorGreaterThanThreshold,
-			stat:       "Average",
-			desc:       "Alert when Lambda execution time is too high",
+			comparison: cwTypes.ComparisonOperatorGreaterThanOrEqualToThreshold,
+			stat:       "Sum",
+			desc:       "Alert when Lambda is being throttled",
 		},
 		{
-			name:       fmt.Sprintf("%s-throttles", cfg.LambdaFuncName),
-			metric:     "Throttles",
-			threshold:  1,
+			name:       dynamoThrottlesAlarm,
+			metric:     "ThrottledRequests",
+			threshold:  cfg.AlarmDynamoThrottleThreshold,
 			period:     300,
 			comparison: cwTypes.ComparisonOperatorGreaterThanOrEqualToThreshold,
 			stat:       "Sum",
-			desc:       "Alert when Lambda is being throttled",
+			desc:       "Alert when the DynamoDB table is throttling requests",
 		},
 	}
 
@@ -110,7 +120,7 @@ func setupAlarms(ctx context.Context, awsCfg aws.Config, cfg *config.Config, sns
 	}
 
 	for _, a := range alarms {
-		log.Printf("[cloudwatch] Creating alarm: %s", a.name)
+		entry.WithField("metric", a.metric).Infof("Creating alarm: %s", a.name)
 		input := &cloudwatch.PutMetricAlarmInput{
 			AlarmName:          aws.String(a.name),
 			AlarmDescription:   aws.String(a.desc),
@@ -129,26 +139,145 @@ func setupAlarms(ctx context.Context, awsCfg aws.Config, cfg *config.Config, sns
 				},
 			},
 		}
+		if a.name == dynamoThrottlesAlarm {
+			input.Namespace = aws.String("AWS/DynamoDB")
+			input.Dimensions = []cwTypes.Dimension{
+				{
+					Name:  aws.String("TableName"),
+					Value: aws.String(cfg.DynamoTableName),
+				},
+			}
+		}
 
 		if len(alarmActions) > 0 {
 			input.AlarmActions = alarmActions
 			input.OKActions = alarmActions
 		}
 
-		_, err := client.PutMetricAlarm(ctx, input)
+		err := utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+			_, putErr := client.PutMetricAlarm(ctx, input)
+			return putErr
+		})
 		if err != nil {
 			return fmt.Errorf("PutMetricAlarm %s: %w", a.name, err)
 		}
 	}
 
-	log.Printf("[cloudwatch] Created %d alarms", len(alarms))
+	if err := setupAnomalyAlarm(ctx, client, cfg, durationAlarm, "AWS/Lambda", "Duration", "Average", alarmActions); err != nil {
+		return fmt.Errorf("Duration anomaly alarm: %w", err)
+	}
+	if err := setupAnomalyAlarm(ctx, client, cfg, fmt.Sprintf("%s-processed-records-anomaly", cfg.LambdaFuncName),
+		"IaC/Lambda", "ProcessedRecords", "Sum", alarmActions); err != nil {
+		return fmt.Errorf("ProcessedRecords anomaly alarm: %w", err)
+	}
+
+	if err := setupCompositeAlarm(ctx, client, cfg, errorsAlarm, durationAlarm, dynamoThrottlesAlarm, alarmActions); err != nil {
+		return fmt.Errorf("composite alarm: %w", err)
+	}
+
+	entry.Infof("Created %d alarms, 2 anomaly detectors, 1 composite alarm", len(alarms))
 	return nil
 }
 
+// setupAnomalyAlarm trains an anomaly-detection model for namespace/metric
+// (scoped to this Lambda function via the FunctionName dimension) and backs
+// it with a PutMetricAlarm whose Metrics field expresses
+// ANOMALY_DETECTION_BAND(m1, cfg.AlarmAnomalyBandWidth) instead of a flat
+// MetricName/Threshold pair, so the alarm fires only when the metric departs
+// from its own recent trend rather than crossing a hardcoded number.
+func setupAnomalyAlarm(ctx context.Context, client *cloudwatch.Client, cfg *config.Config,
+	alarmName, namespace, metric, stat string, alarmActions []string) error {
+
+	dimensions := []cwTypes.Dimension{
+		{Name: aws.String("FunctionName"), Value: aws.String(cfg.LambdaFuncName)},
+	}
+
+	err := utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		_, putErr := client.PutAnomalyDetector(ctx, &cloudwatch.PutAnomalyDetectorInput{
+			Namespace:  aws.String(namespace),
+			MetricName: aws.String(metric),
+			Stat:       aws.String(stat),
+			Dimensions: dimensions,
+		})
+		return putErr
+	})
+	if err != nil {
+		return fmt.Errorf("PutAnomalyDetector %s/%s: %w", namespace, metric, err)
+	}
+
+	band := fmt.Sprintf("ANOMALY_DETECTION_BAND(m1, %g)", cfg.AlarmAnomalyBandWidth)
+	input := &cloudwatch.PutMetricAlarmInput{
+		AlarmName:          aws.String(alarmName),
+		AlarmDescription:   aws.String(fmt.Sprintf("Alert when %s/%s departs from its anomaly-detection band", namespace, metric)),
+		ComparisonOperator: cwTypes.ComparisonOperatorLessThanLowerOrGreaterThanUpperThreshold,
+		EvaluationPeriods:  aws.Int32(2),
+		ThresholdMetricId:  aws.String("ad1"),
+		TreatMissingData:   aws.String("notBreaching"),
+		Metrics: []cwTypes.MetricDataQuery{
+			{
+				Id: aws.String("m1"),
+				MetricStat: &cwTypes.MetricStat{
+					Metric: &cwTypes.Metric{
+						Namespace:  aws.String(namespace),
+						MetricName: aws.String(metric),
+						Dimensions: dimensions,
+					},
+					Period: aws.Int32(300),
+					Stat:   aws.String(stat),
+				},
+				ReturnData: aws.Bool(true),
+			},
+			{
+				Id:         aws.String("ad1"),
+				Expression: aws.String(band),
+				Label:      aws.String(fmt.Sprintf("%s anomaly band", metric)),
+				ReturnData: aws.Bool(true),
+			},
+		},
+	}
+	if len(alarmActions) > 0 {
+		input.AlarmActions = alarmActions
+		input.OKActions = alarmActions
+	}
+
+	return utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		_, putErr := client.PutMetricAlarm(ctx, input)
+		return putErr
+	})
+}
+
+// setupCompositeAlarm wires up the "<funcName>-degraded" composite alarm:
+// ALARM(errors) OR (ALARM(duration) AND ALARM(dynamoThrottles)). Correlating
+// Lambda's own errors with a slow-and-throttled-DynamoDB pattern cuts down on
+// pages that fire from one flaky threshold alone.
+func setupCompositeAlarm(ctx context.Context, client *cloudwatch.Client, cfg *config.Config,
+	errorsAlarm, durationAlarm, dynamoThrottlesAlarm string, alarmActions []string) error {
+
+	alarmRule := fmt.Sprintf(`ALARM("%s") OR (ALARM("%s") AND ALARM("%s"))`,
+		errorsAlarm, durationAlarm, dynamoThrottlesAlarm)
+
+	input := &cloudwatch.PutCompositeAlarmInput{
+		AlarmName:        aws.String(fmt.Sprintf("%s-degraded", cfg.LambdaFuncName)),
+		AlarmDescription: aws.String("Lambda is erroring, or running slow while DynamoDB throttles it"),
+		AlarmRule:        aws.String(alarmRule),
+		ActionsEnabled:   aws.Bool(true),
+	}
+	if len(alarmActions) > 0 {
+		input.AlarmActions = alarmActions
+		input.OKActions = alarmActions
+	}
+
+	return utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		_, putErr := client.PutCompositeAlarm(ctx, input)
+		return putErr
+	})
+}
+
 // ── Dashboard ────────────────────────────────────────────────────────────────
 
 func setupDashboard(ctx context.Context, awsCfg aws.Config, cfg *config.Config) error {
 	client := cloudwatch.NewFromConfig(awsCfg)
+	entry := logging.Step(ctx, "cloudwatch", cfg.LambdaFuncName)
 	dashboardName := fmt.Sprintf("%s-dashboard", cfg.LambdaFuncName)
 
 	// Four widgets: Lambda invocations/errors, duration, DynamoDB ops, custom metrics
@@ -217,30 +346,58 @@ func setupDashboard(ctx context.Context, awsCfg aws.Config, cfg *config.Config)
 		return fmt.Errorf("marshal dashboard: %w", err)
 	}
 
-	_, err = client.PutDashboard(ctx, &cloudwatch.PutDashboardInput{
-		DashboardName: aws.String(dashboardName),
-		DashboardBody: aws.String(string(dashJSON)),
+	err = utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		_, putErr := client.PutDashboard(ctx, &cloudwatch.PutDashboardInput{
+			DashboardName: aws.String(dashboardName),
+			DashboardBody: aws.String(string(dashJSON)),
+		})
+		return putErr
 	})
 	if err != nil {
 		return fmt.Errorf("PutDashboard: %w", err)
 	}
 
-	log.Printf("[cloudwatch] Dashboard created: %s", dashboardName)
+	entry.Infof("Dashboard created: %s", dashboardName)
 	return nil
 }
 
 // DeleteCloudWatchResources removes alarms, dashboard and log group (cleanup helper).
 func DeleteCloudWatchResources(ctx context.Context, awsCfg aws.Config, cfg *config.Config) {
+	entry := logging.Step(ctx, "cloudwatch", cfg.LambdaFuncName)
 	cwClient := cloudwatch.NewFromConfig(awsCfg)
 	logsClient := cloudwatchlogs.NewFromConfig(awsCfg)
 
+	// Composite alarms must be deleted before the alarms they reference.
+	cwClient.DeleteAlarms(ctx, &cloudwatch.DeleteAlarmsInput{ //nolint
+		AlarmNames: []string{fmt.Sprintf("%s-degraded", cfg.LambdaFuncName)},
+	})
+
 	alarmNames := []string{
 		fmt.Sprintf("%s-errors", cfg.LambdaFuncName),
 		fmt.Sprintf("%s-duration", cfg.LambdaFuncName),
 		fmt.Sprintf("%s-throttles", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-dynamo-throttles", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-processed-records-anomaly", cfg.LambdaFuncName),
 	}
 	cwClient.DeleteAlarms(ctx, &cloudwatch.DeleteAlarmsInput{AlarmNames: alarmNames}) //nolint
 
+	cwClient.DeleteAnomalyDetector(ctx, &cloudwatch.DeleteAnomalyDetectorInput{ //nolint
+		Namespace:  aws.String("AWS/Lambda"),
+		MetricName: aws.String("Duration"),
+		Stat:       aws.String("Average"),
+		Dimensions: []cwTypes.Dimension{
+			{Name: aws.String("FunctionName"), Value: aws.String(cfg.LambdaFuncName)},
+		},
+	})
+	cwClient.DeleteAnomalyDetector(ctx, &cloudwatch.DeleteAnomalyDetectorInput{ //nolint
+		Namespace:  aws.String("IaC/Lambda"),
+		MetricName: aws.String("ProcessedRecords"),
+		Stat:       aws.String("Sum"),
+		Dimensions: []cwTypes.Dimension{
+			{Name: aws.String("FunctionName"), Value: aws.String(cfg.LambdaFuncName)},
+		},
+	})
+
 	cwClient.DeleteDashboards(ctx, &cloudwatch.DeleteDashboardsInput{ //nolint
 		DashboardNames: []string{fmt.Sprintf("%s-dashboard", cfg.LambdaFuncName)},
 	})
@@ -249,5 +406,5 @@ func DeleteCloudWatchResources(ctx context.Context, awsCfg aws.Config, cfg *conf
 		LogGroupName: aws.String(cfg.LogGroupName),
 	})
 
-	log.Printf("[cloudwatch] CloudWatch resources deleted")
+	entry.Info("CloudWatch resources deleted")
 }
\ No newline at end of file
@@ -0,0 +1,72 @@
+package infrastructure
+
+import (
+	"context"
+
+	"aws-iac-go/config"
+	"aws-iac-go/infrastructure/state"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// ObserveResources looks each recorded resource up live in AWS by name and
+// returns a Type/Name -> current ARN map suitable for state.DetectDrift.
+// A resource missing from the returned map means it no longer exists;
+// runDestroy calls this right before tearing resources down so an operator
+// sees drift (deleted out-of-band, renamed, recreated with a new ARN) instead
+// of a silent no-op delete.
+func ObserveResources(ctx context.Context, awsCfg aws.Config, cfg *config.Config, resources []state.Resource) map[string]string {
+	observed := make(map[string]string)
+
+	dynamoClient := NewDynamoClient(awsCfg, cfg)
+	iamClient := iam.NewFromConfig(awsCfg)
+	lambdaClient := lambda.NewFromConfig(awsCfg)
+	snsClient := sns.NewFromConfig(awsCfg)
+	logsClient := cloudwatchlogs.NewFromConfig(awsCfg)
+
+	for _, r := range resources {
+		key := r.Type + "/" + r.Name
+
+		switch r.Type {
+		case "dynamodb-table":
+			if arn, err := getTableARN(ctx, dynamoClient, r.Name); err == nil {
+				observed[key] = arn
+			}
+		case "iam-role":
+			if arn, err := getRoleARN(ctx, iamClient, r.Name); err == nil {
+				observed[key] = arn
+			}
+		case "lambda-function":
+			out, err := lambdaClient.GetFunction(ctx, &lambda.GetFunctionInput{
+				FunctionName: aws.String(r.Name),
+			})
+			if err == nil {
+				observed[key] = aws.ToString(out.Configuration.FunctionArn)
+			}
+		case "sns-topic":
+			// SNS has no lookup-by-name API, so this only detects deletion
+			// (via the recorded ARN), not a rename to a different ARN.
+			if _, err := snsClient.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{
+				TopicArn: aws.String(r.ARN),
+			}); err == nil {
+				observed[key] = r.ARN
+			}
+		case "cloudwatch":
+			// Recorded without an ARN — treat "log group still exists" as
+			// "not drifted" so the empty ARN continues to match.
+			out, err := logsClient.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+				LogGroupNamePrefix: aws.String(cfg.LogGroupName),
+			})
+			if err == nil && len(out.LogGroups) > 0 {
+				observed[key] = r.ARN
+			}
+		}
+	}
+
+	return observed
+}
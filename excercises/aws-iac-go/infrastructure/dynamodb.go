@@ -3,11 +3,11 @@ package infrastructure
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"aws-iac-go/config"
+	"aws-iac-go/logging"
 	"aws-iac-go/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -18,52 +18,59 @@ import (
 // SetupDynamoDB creates a DynamoDB table and waits until it becomes active.
 // If the table already exists, it returns its ARN without making any changes.
 func SetupDynamoDB(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (string, error) {
-	client := dynamodb.NewFromConfig(awsCfg)
+	start := time.Now()
+	entry := logging.Step(ctx, "dynamodb", cfg.DynamoTableName)
+	client := NewDynamoClient(awsCfg, cfg)
 
 	// 1. Check whether the table already exists
 	tableARN, err := getTableARN(ctx, client, cfg.DynamoTableName)
 	if err == nil {
-		log.Printf("[dynamodb] Table %s already exists: %s", cfg.DynamoTableName, tableARN)
+		entry.Infof("Table %s already exists: %s", cfg.DynamoTableName, tableARN)
 		return tableARN, nil
 	}
 
 	// 2. Create the table
-	log.Printf("[dynamodb] Creating table: %s", cfg.DynamoTableName)
-	createOut, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: aws.String(cfg.DynamoTableName),
+	entry.Infof("Creating table: %s", cfg.DynamoTableName)
+	var createOut *dynamodb.CreateTableOutput
+	err = utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		var createErr error
+		createOut, createErr = client.CreateTable(ctx, &dynamodb.CreateTableInput{
+			TableName: aws.String(cfg.DynamoTableName),
 
-		// Schema: partition key = "id" (String), sort key = "timestamp" (String)
-		AttributeDefinitions: []types.AttributeDefinition{
-			{AttributeName: aws.String("id"),        AttributeType: types.ScalarAttributeTypeS},
-			{AttributeName: aws.String("timestamp"), AttributeType: types.ScalarAttributeTypeS},
-		},
-		KeySchema: []types.KeySchemaElement{
-			{AttributeName: aws.String("id"),        KeyType: types.KeyTypeHash},
-			{AttributeName: aws.String("timestamp"), KeyType: types.KeyTypeRange},
-		},
-
-		// PAY_PER_REQUEST — no cost when idle, ideal for this use case
-		BillingMode: types.BillingModePayPerRequest,
-
-		Tags: []types.Tag{
-			{Key: aws.String("Project"),   Value: aws.String("iac-go")},
-			{Key: aws.String("ManagedBy"), Value: aws.String("go-sdk")},
-		},
+			// Schema: partition key = "id" (String), sort key = "timestamp" (String)
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("timestamp"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("timestamp"), KeyType: types.KeyTypeRange},
+			},
+
+			// PAY_PER_REQUEST — no cost when idle, ideal for this use case
+			BillingMode: types.BillingModePayPerRequest,
+
+			Tags: []types.Tag{
+				{Key: aws.String("Project"), Value: aws.String("iac-go")},
+				{Key: aws.String("ManagedBy"), Value: aws.String("go-sdk")},
+			},
+		})
+		return createErr
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "ResourceInUseException") {
-			log.Printf("[dynamodb] Table already exists (race condition), fetching ARN")
+			entry.Info("Table already exists (race condition), fetching ARN")
 			return getTableARN(ctx, client, cfg.DynamoTableName)
 		}
 		return "", fmt.Errorf("CreateTable: %w", err)
 	}
 
 	tableARN = aws.ToString(createOut.TableDescription.TableArn)
-	log.Printf("[dynamodb] Table being created, ARN: %s", tableARN)
+	entry.Infof("Table being created, ARN: %s", tableARN)
 
 	// 3. Wait until the table reaches ACTIVE status
-	log.Printf("[dynamodb] Waiting for ACTIVE status...")
-	err = utils.PollUntil(120*time.Second, 5*time.Second, func() (bool, error) {
+	entry.Info("Waiting for ACTIVE status...")
+	err = utils.PollUntil(ctx, 120*time.Second, 5*time.Second, func() (bool, error) {
 		arn, e := getTableARN(ctx, client, cfg.DynamoTableName)
 		if e != nil {
 			return false, nil // not ready yet
@@ -77,33 +84,89 @@ func SetupDynamoDB(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (
 			return false, nil
 		}
 		status := desc.Table.TableStatus
-		log.Printf("[dynamodb] Status: %s", status)
+		entry.Infof("Status: %s", status)
 		return status == types.TableStatusActive, nil
 	})
 	if err != nil {
 		return "", fmt.Errorf("timed out waiting for table: %w", err)
 	}
 
-	// 4. Enable Point-In-Time Recovery
-	log.Printf("[dynamodb] Enabling Point-In-Time Recovery...")
-	_, err = client.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+	// 4. Enable Point-In-Time Recovery — DynamoDB Local / LocalStack don't
+	// implement this API, so skip it rather than log a spurious warning.
+	if UsingLocalDynamo(cfg) {
+		entry.Info("Skipping PITR: not supported against a local endpoint")
+	} else {
+		entry.Info("Enabling Point-In-Time Recovery...")
+		err = utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+			_, backupErr := client.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+				TableName: aws.String(cfg.DynamoTableName),
+				PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+					PointInTimeRecoveryEnabled: aws.Bool(true),
+				},
+			})
+			return backupErr
+		})
+		if err != nil {
+			// Non-fatal — PITR is optional
+			entry.WithField("error", err).Warn("Could not enable PITR")
+		}
+	}
+
+	// 5. Enable TTL on the configured attribute, if requested.
+	if err := setupTTL(ctx, client, cfg); err != nil {
+		// Non-fatal — same treatment as PITR above
+		entry.WithField("error", err).Warn("Could not configure TTL")
+	}
+
+	entry.WithField("elapsed_ms", time.Since(start).Milliseconds()).Infof("Table %s is ready", cfg.DynamoTableName)
+	return tableARN, nil
+}
+
+// setupTTL enables the TTL attribute named by cfg.DynamoTTLAttribute, if set.
+// AWS rejects UpdateTimeToLive calls that re-enable an already-enabled
+// specification, so this first calls DescribeTimeToLive and only issues the
+// update when TTL is disabled or currently points at a different attribute.
+func setupTTL(ctx context.Context, client *dynamodb.Client, cfg *config.Config) error {
+	if cfg.DynamoTTLAttribute == "" {
+		return nil
+	}
+	entry := logging.Step(ctx, "dynamodb", cfg.DynamoTableName)
+
+	desc, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
 		TableName: aws.String(cfg.DynamoTableName),
-		PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
-			PointInTimeRecoveryEnabled: aws.Bool(true),
-		},
 	})
 	if err != nil {
-		// Non-fatal — PITR is optional
-		log.Printf("[dynamodb] WARN: Could not enable PITR: %v", err)
+		return fmt.Errorf("DescribeTimeToLive: %w", err)
 	}
 
-	log.Printf("[dynamodb] Table %s is ready", cfg.DynamoTableName)
-	return tableARN, nil
+	status := desc.TimeToLiveDescription.TimeToLiveStatus
+	current := aws.ToString(desc.TimeToLiveDescription.AttributeName)
+	if status == types.TimeToLiveStatusEnabled && current == cfg.DynamoTTLAttribute {
+		entry.Infof("TTL already enabled on %q", cfg.DynamoTTLAttribute)
+		return nil
+	}
+
+	entry.Infof("Enabling TTL on attribute %q...", cfg.DynamoTTLAttribute)
+	err = utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		_, ttlErr := client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(cfg.DynamoTableName),
+			TimeToLiveSpecification: &types.TimeToLiveSpecification{
+				AttributeName: aws.String(cfg.DynamoTTLAttribute),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		return ttlErr
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateTimeToLive: %w", err)
+	}
+	return nil
 }
 
 // DynamoDBHealthCheck verifies that the table is active and accessible.
 func DynamoDBHealthCheck(ctx context.Context, awsCfg aws.Config, cfg *config.Config) error {
-	client := dynamodb.NewFromConfig(awsCfg)
+	entry := logging.Step(ctx, "dynamodb", cfg.DynamoTableName)
+	client := NewDynamoClient(awsCfg, cfg)
 	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
 		TableName: aws.String(cfg.DynamoTableName),
 	})
@@ -114,8 +177,18 @@ func DynamoDBHealthCheck(ctx context.Context, awsCfg aws.Config, cfg *config.Con
 		return fmt.Errorf("table %s is not ACTIVE (status: %s)",
 			cfg.DynamoTableName, desc.Table.TableStatus)
 	}
-	log.Printf("[dynamodb] Health check OK — table ACTIVE, item count: %d",
-		desc.Table.ItemCount)
+
+	ttlStatus := "n/a"
+	if ttlDesc, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(cfg.DynamoTableName),
+	}); err == nil {
+		ttlStatus = fmt.Sprintf("%s (attribute: %q)",
+			ttlDesc.TimeToLiveDescription.TimeToLiveStatus,
+			aws.ToString(ttlDesc.TimeToLiveDescription.AttributeName))
+	}
+
+	entry.Infof("Health check OK — table ACTIVE, item count: %d, TTL: %s",
+		desc.Table.ItemCount, ttlStatus)
 	return nil
 }
 
@@ -132,13 +205,17 @@ func getTableARN(ctx context.Context, client *dynamodb.Client, tableName string)
 
 // DeleteDynamoTable removes the DynamoDB table (cleanup helper).
 func DeleteDynamoTable(ctx context.Context, awsCfg aws.Config, cfg *config.Config) error {
-	client := dynamodb.NewFromConfig(awsCfg)
-	_, err := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{
-		TableName: aws.String(cfg.DynamoTableName),
+	entry := logging.Step(ctx, "dynamodb", cfg.DynamoTableName)
+	client := NewDynamoClient(awsCfg, cfg)
+	err := utils.RetryOnAWSThrottle(ctx, cfg.MaxRetries, func() error {
+		_, deleteErr := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{
+			TableName: aws.String(cfg.DynamoTableName),
+		})
+		return deleteErr
 	})
 	if err != nil && !strings.Contains(err.Error(), "ResourceNotFoundException") {
 		return fmt.Errorf("DeleteTable: %w", err)
 	}
-	log.Printf("[dynamodb] Table %s deleted", cfg.DynamoTableName)
+	entry.Infof("Table %s deleted", cfg.DynamoTableName)
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,184 @@
+// Package dynamostore provides data-plane helpers (batch writes, batch gets,
+// paginated scans) on top of a table provisioned by infrastructure.SetupDynamoDB,
+// so callers don't have to hand-roll PutItem/Scan loops and unprocessed-item
+// retry logic themselves.
+package dynamostore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// batchWriteLimit is the BatchWriteItem hard cap on requests per call.
+const batchWriteLimit = 25
+
+// batchGetLimit is the BatchGetItem hard cap on keys per call.
+const batchGetLimit = 100
+
+// backoffBase and backoffCap bound the full-jitter backoff used while
+// draining UnprocessedItems / UnprocessedKeys.
+const (
+	backoffBase = 500 * time.Millisecond
+	backoffCap  = 10 * time.Second
+)
+
+// Store provides batch read/write helpers against a single DynamoDB table.
+type Store struct {
+	client     *dynamodb.Client
+	table      string
+	maxRetries int
+}
+
+// New returns a Store backed by client, scoped to table. maxRetries bounds
+// the BatchWriteItem/BatchGetItem calls made per chunk, the first submission
+// included, before BatchWrite/BatchGet give up on unprocessed items.
+func New(client *dynamodb.Client, table string, maxRetries int) *Store {
+	return &Store{client: client, table: table, maxRetries: maxRetries}
+}
+
+// BatchWrite writes items in groups of 25 (the BatchWriteItem limit),
+// re-submitting any UnprocessedItems DynamoDB hands back with full-jitter
+// backoff until they drain or maxRetries is exhausted.
+func (s *Store) BatchWrite(ctx context.Context, items []map[string]types.AttributeValue) error {
+	for start := 0; start < len(items); start += batchWriteLimit {
+		end := start + batchWriteLimit
+		if end > len(items) {
+			end = len(items)
+		}
+		if err := s.batchWriteChunk(ctx, items[start:end]); err != nil {
+			return fmt.Errorf("BatchWrite[%d:%d]: %w", start, end, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) batchWriteChunk(ctx context.Context, chunk []map[string]types.AttributeValue) error {
+	requests := make([]types.WriteRequest, len(chunk))
+	for i, item := range chunk {
+		requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+	}
+	pending := map[string][]types.WriteRequest{s.table: requests}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			wait := fullJitterBackoff(attempt)
+			log.Printf("[dynamostore] %d unprocessed items, retrying in %s (attempt %d/%d)",
+				len(pending[s.table]), wait, attempt, s.maxRetries)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("aborted: %w", ctx.Err())
+			case <-time.After(wait):
+			}
+		}
+		if attempt >= s.maxRetries {
+			return fmt.Errorf("gave up after %d attempts with %d items still unprocessed", attempt, len(pending[s.table]))
+		}
+
+		out, err := s.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return fmt.Errorf("BatchWriteItem: %w", err)
+		}
+		pending = out.UnprocessedItems
+	}
+	return nil
+}
+
+// BatchGet fetches keys in groups of 100 (the BatchGetItem limit),
+// re-submitting any UnprocessedKeys with full-jitter backoff until they
+// drain or maxRetries is exhausted.
+func (s *Store) BatchGet(ctx context.Context, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	var results []map[string]types.AttributeValue
+	for start := 0; start < len(keys); start += batchGetLimit {
+		end := start + batchGetLimit
+		if end > len(keys) {
+			end = len(keys)
+		}
+		items, err := s.batchGetChunk(ctx, keys[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("BatchGet[%d:%d]: %w", start, end, err)
+		}
+		results = append(results, items...)
+	}
+	return results, nil
+}
+
+func (s *Store) batchGetChunk(ctx context.Context, chunk []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	pending := map[string]types.KeysAndAttributes{s.table: {Keys: chunk}}
+	var results []map[string]types.AttributeValue
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			wait := fullJitterBackoff(attempt)
+			log.Printf("[dynamostore] %d unprocessed keys, retrying in %s (attempt %d/%d)",
+				len(pending[s.table].Keys), wait, attempt, s.maxRetries)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("aborted: %w", ctx.Err())
+			case <-time.After(wait):
+			}
+		}
+		if attempt >= s.maxRetries {
+			return nil, fmt.Errorf("gave up after %d attempts with %d keys still unprocessed", attempt, len(pending[s.table].Keys))
+		}
+
+		out, err := s.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+		if err != nil {
+			return nil, fmt.Errorf("BatchGetItem: %w", err)
+		}
+		results = append(results, out.Responses[s.table]...)
+		pending = out.UnprocessedKeys
+	}
+	return results, nil
+}
+
+// ScanAll scans the whole table, applying filter (the zero value means no
+// filter), transparently following LastEvaluatedKey pagination and streaming
+// each item to out. Closes out when the scan completes or fails.
+func (s *Store) ScanAll(ctx context.Context, filter expression.Expression, out chan<- map[string]types.AttributeValue) error {
+	defer close(out)
+
+	var startKey map[string]types.AttributeValue
+	for {
+		input := &dynamodb.ScanInput{
+			TableName:                 &s.table,
+			FilterExpression:          filter.Filter(),
+			ExpressionAttributeNames:  filter.Names(),
+			ExpressionAttributeValues: filter.Values(),
+			ExclusiveStartKey:         startKey,
+		}
+
+		page, err := s.client.Scan(ctx, input)
+		if err != nil {
+			return fmt.Errorf("Scan: %w", err)
+		}
+
+		for _, item := range page.Items {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return fmt.Errorf("aborted: %w", ctx.Err())
+			}
+		}
+
+		if page.LastEvaluatedKey == nil {
+			return nil
+		}
+		startKey = page.LastEvaluatedKey
+	}
+}
+
+// fullJitterBackoff returns a random duration in [0, min(backoffCap, backoffBase*2^attempt)].
+func fullJitterBackoff(attempt int) time.Duration {
+	maxWait := backoffBase * time.Duration(1<<uint(attempt))
+	if maxWait > backoffCap {
+		maxWait = backoffCap
+	}
+	return time.Duration(rand.Int63n(int64(maxWait) + 1))
+}
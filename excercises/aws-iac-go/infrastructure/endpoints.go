@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"aws-iac-go/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// GlobalEndpointResolver returns an aws.EndpointResolverWithOptionsFunc that
+// routes every AWS service to cfg.LocalstackEndpoint when set, so the whole
+// bootstrap flow (SNS, IAM, DynamoDB, Lambda, CloudWatch) can run against a
+// single LocalStack container in CI. Meant to be attached once to the shared
+// aws.Config in main.go. Returns nil when LocalstackEndpoint is unset, so
+// callers fall through to the SDK's normal endpoint resolution.
+func GlobalEndpointResolver(cfg *config.Config) aws.EndpointResolverWithOptionsFunc {
+	if cfg.LocalstackEndpoint == "" {
+		return nil
+	}
+	return func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		return aws.Endpoint{URL: cfg.LocalstackEndpoint, SigningRegion: region}, nil
+	}
+}
+
+// NewDynamoClient builds the DynamoDB client used throughout the package. When
+// cfg.DynamoEndpoint is set (e.g. http://localhost:8000 for DynamoDB Local)
+// it overrides just this client's endpoint, taking precedence over a
+// LocalstackEndpoint set on awsCfg — useful for running DynamoDB Local
+// standalone without the rest of LocalStack.
+func NewDynamoClient(awsCfg aws.Config, cfg *config.Config) *dynamodb.Client {
+	return dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		if cfg.DynamoEndpoint == "" {
+			return
+		}
+		o.EndpointResolverWithOptions = aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: cfg.DynamoEndpoint, SigningRegion: region}, nil
+			})
+	})
+}
+
+// UsingLocalDynamo reports whether DynamoDB is pointed at a local/offline
+// endpoint (DynamoDB Local or LocalStack) that doesn't implement every AWS
+// API — notably Point-In-Time Recovery and resource tagging — so callers can
+// skip those calls instead of failing the whole deploy on them.
+func UsingLocalDynamo(cfg *config.Config) bool {
+	return cfg.DynamoEndpoint != "" || cfg.LocalstackEndpoint != ""
+}
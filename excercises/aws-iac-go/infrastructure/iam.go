@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"aws-iac-go/config"
+	"aws-iac-go/logging"
 	"aws-iac-go/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -21,9 +22,12 @@ import (
 // (useful when the caller lacks iam:CreateRole permissions).
 // Returns the role ARN ready to be used when creating the Lambda function.
 func SetupIAM(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (string, error) {
+	start := time.Now()
+	entry := logging.Step(ctx, "iam", cfg.LambdaRoleName)
+
 	// Allow bypassing IAM creation by supplying a pre-existing role ARN
 	if cfg.LambdaRoleARN != "" {
-		log.Printf("[iam] Using pre-existing role ARN from LAMBDA_ROLE_ARN: %s", cfg.LambdaRoleARN)
+		entry.Infof("Using pre-existing role ARN from LAMBDA_ROLE_ARN: %s", cfg.LambdaRoleARN)
 		return cfg.LambdaRoleARN, nil
 	}
 
@@ -32,12 +36,12 @@ func SetupIAM(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (strin
 	// 1. Check whether the role already exists
 	roleARN, err := getRoleARN(ctx, client, cfg.LambdaRoleName)
 	if err == nil {
-		log.Printf("[iam] Role %s already exists: %s", cfg.LambdaRoleName, roleARN)
+		entry.Infof("Role %s already exists: %s", cfg.LambdaRoleName, roleARN)
 		return roleARN, nil
 	}
 
 	// 2. Create the role with a trust policy allowing Lambda to assume it
-	log.Printf("[iam] Creating role: %s", cfg.LambdaRoleName)
+	entry.Infof("Creating role: %s", cfg.LambdaRoleName)
 	trustPolicy := buildTrustPolicy()
 	trustJSON, _ := json.Marshal(trustPolicy)
 
@@ -52,20 +56,28 @@ func SetupIAM(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (strin
 	}
 	// Attach permissions boundary if required by the account policy
 	if cfg.PermissionsBoundaryARN != "" {
-		log.Printf("[iam] Attaching permissions boundary: %s", cfg.PermissionsBoundaryARN)
+		entry.Infof("Attaching permissions boundary: %s", cfg.PermissionsBoundaryARN)
 		createInput.PermissionsBoundary = aws.String(cfg.PermissionsBoundaryARN)
 	}
-	createOut, err := client.CreateRole(ctx, createInput)
+	var createOut *iam.CreateRoleOutput
+	err = utils.RetryWithBackoff(ctx, cfg.MaxRetries, func() error {
+		var createErr error
+		createOut, createErr = client.CreateRole(ctx, createInput)
+		return createErr
+	})
 	if err != nil {
 		return "", fmt.Errorf("CreateRole: %w", err)
 	}
 	roleARN = aws.ToString(createOut.Role.Arn)
-	log.Printf("[iam] Role created: %s", roleARN)
+	entry.Infof("Role created: %s", roleARN)
 
 	// 3. Attach managed policy: basic Lambda execution (CloudWatch Logs)
-	_, err = client.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
-		RoleName:  aws.String(cfg.LambdaRoleName),
-		PolicyArn: aws.String("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"),
+	err = utils.RetryWithBackoff(ctx, cfg.MaxRetries, func() error {
+		_, attachErr := client.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
+			RoleName:  aws.String(cfg.LambdaRoleName),
+			PolicyArn: aws.String("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"),
+		})
+		return attachErr
 	})
 	if err != nil {
 		return "", fmt.Errorf("AttachRolePolicy (basic): %w", err)
@@ -74,28 +86,32 @@ func SetupIAM(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (strin
 	// 4. Attach inline policy: DynamoDB + CloudWatch custom metrics
 	inlinePolicy := buildInlinePolicy(cfg.DynamoTableName, cfg.AWSRegion)
 	inlineJSON, _ := json.Marshal(inlinePolicy)
-	_, err = client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
-		RoleName:       aws.String(cfg.LambdaRoleName),
-		PolicyName:     aws.String("iac-lambda-permissions"),
-		PolicyDocument: aws.String(string(inlineJSON)),
+	err = utils.RetryWithBackoff(ctx, cfg.MaxRetries, func() error {
+		_, putErr := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+			RoleName:       aws.String(cfg.LambdaRoleName),
+			PolicyName:     aws.String("iac-lambda-permissions"),
+			PolicyDocument: aws.String(string(inlineJSON)),
+		})
+		return putErr
 	})
 	if err != nil {
 		return "", fmt.Errorf("PutRolePolicy: %w", err)
 	}
-	log.Printf("[iam] Policies attached to role %s", cfg.LambdaRoleName)
+	entry.Infof("Policies attached to role %s", cfg.LambdaRoleName)
 
 	// 5. Wait for IAM propagation (~10s minimum)
-	log.Printf("[iam] Waiting for IAM propagation...")
-	err = utils.PollUntil(60*time.Second, 5*time.Second, func() (bool, error) {
+	entry.Info("Waiting for IAM propagation...")
+	err = utils.PollUntil(ctx, 60*time.Second, 5*time.Second, func() (bool, error) {
 		_, e := getRoleARN(ctx, client, cfg.LambdaRoleName)
 		return e == nil, nil
 	})
 	if err != nil {
 		return "", fmt.Errorf("IAM propagation timeout: %w", err)
 	}
-	// Extra 10s buffer â€” IAM can be slow to propagate for AssumeRole
+	// Extra 10s buffer — IAM can be slow to propagate for AssumeRole
 	time.Sleep(10 * time.Second)
 
+	entry.WithField("elapsed_ms", time.Since(start).Milliseconds()).Info("IAM setup complete")
 	return roleARN, nil
 }
 
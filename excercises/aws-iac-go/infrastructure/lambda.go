@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"aws-iac-go/config"
+	"aws-iac-go/infrastructure/s3pkg"
+	"aws-iac-go/logging"
 	"aws-iac-go/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -19,22 +21,34 @@ import (
 // SetupLambda packages the Python handler into a ZIP and creates or updates the Lambda function.
 // Returns the function ARN.
 func SetupLambda(ctx context.Context, awsCfg aws.Config, cfg *config.Config, roleARN string) (string, error) {
+	start := time.Now()
+	entry := logging.Step(ctx, "lambda", cfg.LambdaFuncName)
 	client := lambda.NewFromConfig(awsCfg)
 
 	// 1. Package handler.py into a ZIP archive
-	log.Printf("[lambda] Packaging handler.py into ZIP...")
+	entry.Info("Packaging handler.py into ZIP...")
 	zipBytes, err := packageLambdaCode()
 	if err != nil {
 		return "", fmt.Errorf("packageLambdaCode: %w", err)
 	}
-	log.Printf("[lambda] ZIP ready, size: %d bytes", len(zipBytes))
+	entry.Infof("ZIP ready, size: %d bytes", len(zipBytes))
+
+	// 1b. When DeploymentBucket is set the artifact goes through the S3
+	// deployment bucket instead of the inline ZipFile payload (which caps out
+	// at ~50 MB); the choice does not depend on the ZIP's size.
+	code, err := buildFunctionCode(ctx, awsCfg, cfg, zipBytes)
+	if err != nil {
+		return "", err
+	}
 
 	// 2. Environment variables injected into the Lambda function.
 	// Note: AWS_REGION is reserved by Lambda and must not be set manually.
 	envVars := map[string]string{
-		"DYNAMODB_TABLE_NAME": cfg.DynamoTableName,
-		"EXTERNAL_API_URL":    cfg.ExternalAPIURL,
-		"MAX_RETRIES":         fmt.Sprintf("%d", cfg.MaxRetries),
+		"DYNAMODB_TABLE_NAME":  cfg.DynamoTableName,
+		"EXTERNAL_API_URL":     cfg.ExternalAPIURL,
+		"MAX_RETRIES":          fmt.Sprintf("%d", cfg.MaxRetries),
+		"DYNAMO_TTL_ATTRIBUTE": cfg.DynamoTTLAttribute,
+		"DYNAMO_TTL_DAYS":      fmt.Sprintf("%d", cfg.DynamoTTLDays),
 	}
 
 	// 3. Check whether the function already exists
@@ -45,55 +59,72 @@ func SetupLambda(ctx context.Context, awsCfg aws.Config, cfg *config.Config, rol
 	if err == nil {
 		// Function exists — update code and configuration
 		funcARN := aws.ToString(existing.Configuration.FunctionArn)
-		log.Printf("[lambda] Function exists, updating: %s", funcARN)
-		return updateLambda(ctx, client, cfg, zipBytes, envVars, funcARN)
+		entry.Infof("Function exists, updating: %s", funcARN)
+		return updateLambda(ctx, client, cfg, code, envVars, funcARN)
 	}
 
-	// 4. Create a new function
-	log.Printf("[lambda] Creating function: %s", cfg.LambdaFuncName)
-	createOut, err := client.CreateFunction(ctx, &lambda.CreateFunctionInput{
-		FunctionName: aws.String(cfg.LambdaFuncName),
-		Description:  aws.String("Fetches external API data and stores in DynamoDB"),
-		Runtime:      lambdaTypes.RuntimePython312,
-		Handler:      aws.String("handler.lambda_handler"),
-		Role:         aws.String(roleARN),
-		Timeout:      aws.Int32(60),
-		MemorySize:   aws.Int32(256),
-		Code: &lambdaTypes.FunctionCode{
-			ZipFile: zipBytes,
-		},
-		Environment: &lambdaTypes.Environment{
-			Variables: envVars,
-		},
-		Tags: map[string]string{
-			"Project":   "iac-go",
-			"ManagedBy": "go-sdk",
-		},
+	// 4. Create a new function. Retried with backoff because IAM role
+	// propagation can make CreateFunction return InvalidParameterValueException
+	// for a few seconds right after SetupIAM creates the role.
+	entry.Infof("Creating function: %s", cfg.LambdaFuncName)
+	var createOut *lambda.CreateFunctionOutput
+	err = utils.RetryWithBackoff(ctx, cfg.MaxRetries, func() error {
+		var createErr error
+		createOut, createErr = client.CreateFunction(ctx, &lambda.CreateFunctionInput{
+			FunctionName: aws.String(cfg.LambdaFuncName),
+			Description:  aws.String("Fetches external API data and stores in DynamoDB"),
+			Runtime:      lambdaTypes.RuntimePython312,
+			Handler:      aws.String("handler.lambda_handler"),
+			Role:         aws.String(roleARN),
+			Timeout:      aws.Int32(60),
+			MemorySize:   aws.Int32(256),
+			Code:         code,
+			Environment: &lambdaTypes.Environment{
+				Variables: envVars,
+			},
+			Publish: true, // immediately publish version 1 so an alias can target it
+			Tags: map[string]string{
+				"Project":   "iac-go",
+				"ManagedBy": "go-sdk",
+			},
+		})
+		return createErr
 	})
 	if err != nil {
 		return "", fmt.Errorf("CreateFunction: %w", err)
 	}
 
 	funcARN := aws.ToString(createOut.FunctionArn)
-	log.Printf("[lambda] Function being created: %s", funcARN)
+	entry.Infof("Function being created: %s", funcARN)
 
 	// 5. Wait until the function state becomes Active
 	if err = waitForLambdaActive(ctx, client, cfg.LambdaFuncName); err != nil {
 		return "", err
 	}
 
-	log.Printf("[lambda] Function %s is ready", cfg.LambdaFuncName)
+	// 6. Point the live alias at the version we just published
+	if err = ensureAlias(ctx, client, cfg, aws.ToString(createOut.Version)); err != nil {
+		return "", err
+	}
+
+	entry.WithField("elapsed_ms", time.Since(start).Milliseconds()).Infof("Function %s is ready", cfg.LambdaFuncName)
 	return funcARN, nil
 }
 
-// updateLambda updates the code and configuration of an existing Lambda function.
+// updateLambda updates the code and configuration of an existing Lambda function,
+// publishes a new version, and points the live alias at it.
 func updateLambda(ctx context.Context, client *lambda.Client, cfg *config.Config,
-	zipBytes []byte, envVars map[string]string, funcARN string) (string, error) {
+	code *lambdaTypes.FunctionCode, envVars map[string]string, funcARN string) (string, error) {
 
-	// Update function code
+	// Update function code first, but don't publish yet — publishing here
+	// would snapshot the new code against the *old* environment variables,
+	// since the configuration update below hasn't landed yet.
 	_, err := client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
-		FunctionName: aws.String(cfg.LambdaFuncName),
-		ZipFile:      zipBytes,
+		FunctionName:    aws.String(cfg.LambdaFuncName),
+		ZipFile:         code.ZipFile,
+		S3Bucket:        code.S3Bucket,
+		S3Key:           code.S3Key,
+		S3ObjectVersion: code.S3ObjectVersion,
 	})
 	if err != nil {
 		return "", fmt.Errorf("UpdateFunctionCode: %w", err)
@@ -116,15 +147,32 @@ func updateLambda(ctx context.Context, client *lambda.Client, cfg *config.Config
 	if err != nil {
 		return "", fmt.Errorf("UpdateFunctionConfiguration: %w", err)
 	}
+	if err = waitForLambdaActive(ctx, client, cfg.LambdaFuncName); err != nil {
+		return "", err
+	}
 
-	log.Printf("[lambda] Function updated: %s", funcARN)
+	// Publish now that $LATEST carries both the new code and the new
+	// configuration, so the version we alias actually reflects both.
+	publishOut, err := client.PublishVersion(ctx, &lambda.PublishVersionInput{
+		FunctionName: aws.String(cfg.LambdaFuncName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("PublishVersion: %w", err)
+	}
+
+	if err = ensureAlias(ctx, client, cfg, aws.ToString(publishOut.Version)); err != nil {
+		return "", err
+	}
+
+	logging.Step(ctx, "lambda", cfg.LambdaFuncName).Infof("Function updated: %s", funcARN)
 	return funcARN, nil
 }
 
 // waitForLambdaActive polls the function state until it reaches Active.
 func waitForLambdaActive(ctx context.Context, client *lambda.Client, funcName string) error {
-	log.Printf("[lambda] Waiting for Active state...")
-	return utils.PollUntil(120*time.Second, 5*time.Second, func() (bool, error) {
+	entry := logging.Step(ctx, "lambda", funcName)
+	entry.Info("Waiting for Active state...")
+	return utils.PollUntil(ctx, 120*time.Second, 5*time.Second, func() (bool, error) {
 		out, err := client.GetFunction(ctx, &lambda.GetFunctionInput{
 			FunctionName: aws.String(funcName),
 		})
@@ -133,7 +181,7 @@ func waitForLambdaActive(ctx context.Context, client *lambda.Client, funcName st
 		}
 		state := out.Configuration.State
 		lastUpdate := out.Configuration.LastUpdateStatus
-		log.Printf("[lambda] State: %s (LastUpdate: %s)", state, lastUpdate)
+		entry.Infof("State: %s (LastUpdate: %s)", state, lastUpdate)
 
 		// Ready when Active and last update is no longer InProgress
 		return state == lambdaTypes.StateActive &&
@@ -168,13 +216,63 @@ func packageLambdaCode() ([]byte, error) {
 	})
 }
 
-// InvokeLambdaTest performs a test invocation of the Lambda function and logs the result.
+// buildFunctionCode decides how the ZIP reaches Lambda: inline as ZipFile for
+// small ad-hoc deploys, or via the S3 deployment bucket (skipping re-upload
+// when the object already matches by SHA256) once Config.DeploymentBucket is set.
+func buildFunctionCode(ctx context.Context, awsCfg aws.Config, cfg *config.Config, zipBytes []byte) (*lambdaTypes.FunctionCode, error) {
+	if cfg.DeploymentBucket == "" {
+		return &lambdaTypes.FunctionCode{ZipFile: zipBytes}, nil
+	}
+
+	if err := s3pkg.EnsureBucket(ctx, awsCfg, cfg.DeploymentBucket, cfg.AWSRegion); err != nil {
+		return nil, fmt.Errorf("EnsureBucket: %w", err)
+	}
+
+	key := fmt.Sprintf("%s.zip", cfg.LambdaFuncName)
+	result, err := s3pkg.Upload(ctx, awsCfg, cfg.DeploymentBucket, key, zipBytes)
+	if err != nil {
+		return nil, fmt.Errorf("s3pkg.Upload: %w", err)
+	}
+
+	return &lambdaTypes.FunctionCode{
+		S3Bucket:        aws.String(result.Bucket),
+		S3Key:           aws.String(result.Key),
+		S3ObjectVersion: aws.String(result.VersionID),
+	}, nil
+}
+
+// PackageOnly builds the Lambda ZIP and uploads it to the S3 deployment
+// bucket without touching the function itself, for CI pipelines that package
+// an artifact ahead of a separate deploy step.
+func PackageOnly(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (*s3pkg.UploadResult, error) {
+	if cfg.DeploymentBucket == "" {
+		return nil, fmt.Errorf("--package-only requires DEPLOYMENT_BUCKET to be set")
+	}
+
+	zipBytes, err := packageLambdaCode()
+	if err != nil {
+		return nil, fmt.Errorf("packageLambdaCode: %w", err)
+	}
+
+	if err := s3pkg.EnsureBucket(ctx, awsCfg, cfg.DeploymentBucket, cfg.AWSRegion); err != nil {
+		return nil, fmt.Errorf("EnsureBucket: %w", err)
+	}
+
+	key := fmt.Sprintf("%s.zip", cfg.LambdaFuncName)
+	return s3pkg.Upload(ctx, awsCfg, cfg.DeploymentBucket, key, zipBytes)
+}
+
+// InvokeLambdaTest performs a test invocation of the Lambda function's live
+// alias (rather than $LATEST) so tests exercise whatever version is actually
+// receiving traffic, including during a canary/linear shift.
 func InvokeLambdaTest(ctx context.Context, awsCfg aws.Config, cfg *config.Config) error {
+	entry := logging.Step(ctx, "lambda", cfg.LambdaFuncName)
 	client := lambda.NewFromConfig(awsCfg)
-	log.Printf("[lambda] Test invocation of %s...", cfg.LambdaFuncName)
+	entry.Infof("Test invocation of %s:%s...", cfg.LambdaFuncName, cfg.LambdaAliasName)
 
 	out, err := client.Invoke(ctx, &lambda.InvokeInput{
 		FunctionName: aws.String(cfg.LambdaFuncName),
+		Qualifier:    aws.String(cfg.LambdaAliasName),
 		Payload:      []byte(`{}`),
 	})
 	if err != nil {
@@ -182,10 +280,10 @@ func InvokeLambdaTest(ctx context.Context, awsCfg aws.Config, cfg *config.Config
 	}
 
 	if out.FunctionError != nil {
-		log.Printf("[lambda] WARN: Function returned an error: %s | Payload: %s",
+		entry.Warnf("Function returned an error: %s | Payload: %s",
 			aws.ToString(out.FunctionError), string(out.Payload))
 	} else {
-		log.Printf("[lambda] Test OK (StatusCode: %d) | Payload: %s",
+		entry.Infof("Test OK (StatusCode: %d) | Payload: %s",
 			out.StatusCode, string(out.Payload))
 	}
 	return nil
@@ -200,6 +298,6 @@ func DeleteLambdaFunction(ctx context.Context, awsCfg aws.Config, cfg *config.Co
 	if err != nil && !strings.Contains(err.Error(), "ResourceNotFoundException") {
 		return fmt.Errorf("DeleteFunction: %w", err)
 	}
-	log.Printf("[lambda] Function %s deleted", cfg.LambdaFuncName)
+	logging.Step(ctx, "lambda", cfg.LambdaFuncName).Infof("Function %s deleted", cfg.LambdaFuncName)
 	return nil
 }
\ No newline at end of file
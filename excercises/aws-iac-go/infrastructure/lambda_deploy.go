@@ -0,0 +1,242 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"aws-iac-go/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// RoutingStrategy selects how traffic is shifted from the alias's current
+// version to a newly published one.
+type RoutingStrategy string
+
+const (
+	// RoutingCanary sends Config.LambdaCanaryPercent of traffic to the new
+	// version for Config.LambdaBakeMinutes, then promotes it fully.
+	RoutingCanary RoutingStrategy = "canary"
+	// RoutingLinear steps traffic to the new version by Config.LambdaCanaryPercent
+	// every Config.LambdaBakeMinutes until it reaches 100%.
+	RoutingLinear RoutingStrategy = "linear"
+)
+
+// RoutingConfig describes a traffic-shifting deploy.
+type RoutingConfig struct {
+	Strategy RoutingStrategy
+}
+
+// ensureAlias creates the alias on first deploy, or advances it straight to
+// version with no weighted routing on subsequent direct (non-shifted)
+// deploys — a plain `go run .` always exercises what it just shipped.
+// Use DeployLambdaWithShift instead of a direct deploy when you want the
+// alias to move gradually behind a bake period.
+func ensureAlias(ctx context.Context, client *lambda.Client, cfg *config.Config, version string) error {
+	_, err := client.GetAlias(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(cfg.LambdaFuncName),
+		Name:         aws.String(cfg.LambdaAliasName),
+	})
+	if err != nil {
+		log.Printf("[lambda] Creating alias %s -> version %s", cfg.LambdaAliasName, version)
+		_, err = client.CreateAlias(ctx, &lambda.CreateAliasInput{
+			FunctionName:    aws.String(cfg.LambdaFuncName),
+			Name:            aws.String(cfg.LambdaAliasName),
+			FunctionVersion: aws.String(version),
+		})
+		if err != nil {
+			return fmt.Errorf("CreateAlias: %w", err)
+		}
+		return nil
+	}
+
+	log.Printf("[lambda] Alias %s exists — advancing it to version %s", cfg.LambdaAliasName, version)
+	return promote(ctx, client, cfg, version)
+}
+
+// DeployLambdaWithShift packages handler.py, publishes it as a new version and shifts
+// the live alias onto it according to routing.Strategy, watching the alarms
+// created by SetupCloudWatch during the bake period and automatically
+// rolling back if any of them enter ALARM state.
+func DeployLambdaWithShift(ctx context.Context, awsCfg aws.Config, cfg *config.Config, routing RoutingConfig) error {
+	client := lambda.NewFromConfig(awsCfg)
+
+	zipBytes, err := packageLambdaCode()
+	if err != nil {
+		return fmt.Errorf("packageLambdaCode: %w", err)
+	}
+
+	// Route through the same S3 deployment-bucket path as a direct deploy
+	// (buildFunctionCode) instead of inlining ZipFile, so a shifted deploy
+	// doesn't hit the ~50 MB inline cap that S3-backed packaging exists to avoid.
+	code, err := buildFunctionCode(ctx, awsCfg, cfg, zipBytes)
+	if err != nil {
+		return err
+	}
+
+	updateOut, err := client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
+		FunctionName:    aws.String(cfg.LambdaFuncName),
+		ZipFile:         code.ZipFile,
+		S3Bucket:        code.S3Bucket,
+		S3Key:           code.S3Key,
+		S3ObjectVersion: code.S3ObjectVersion,
+		Publish:         true,
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateFunctionCode: %w", err)
+	}
+	if err = waitForLambdaActive(ctx, client, cfg.LambdaFuncName); err != nil {
+		return err
+	}
+	newVersion := aws.ToString(updateOut.Version)
+
+	aliasOut, err := client.GetAlias(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(cfg.LambdaFuncName),
+		Name:         aws.String(cfg.LambdaAliasName),
+	})
+	if err != nil {
+		return fmt.Errorf("GetAlias: %w", err)
+	}
+	previousVersion := aws.ToString(aliasOut.FunctionVersion)
+	log.Printf("[lambda] Shifting %s from version %s to %s via %s", cfg.LambdaAliasName, previousVersion, newVersion, routing.Strategy)
+
+	switch routing.Strategy {
+	case RoutingLinear:
+		return shiftLinear(ctx, client, awsCfg, cfg, previousVersion, newVersion)
+	default:
+		return shiftCanary(ctx, client, awsCfg, cfg, previousVersion, newVersion)
+	}
+}
+
+// shiftCanary sends LambdaCanaryPercent of traffic to newVersion, bakes for
+// LambdaBakeMinutes watching alarms, then promotes or rolls back.
+func shiftCanary(ctx context.Context, client *lambda.Client, awsCfg aws.Config, cfg *config.Config, previousVersion, newVersion string) error {
+	if err := setAliasWeight(ctx, client, cfg, previousVersion, newVersion, cfg.LambdaCanaryPercent); err != nil {
+		return err
+	}
+
+	if alarmed, err := bakeAndWatch(ctx, awsCfg, cfg, cfg.LambdaBakeMinutes); err != nil {
+		return err
+	} else if alarmed {
+		log.Printf("[lambda] Alarm triggered during canary bake — rolling back to version %s", previousVersion)
+		return RollbackLambda(ctx, awsCfg, cfg, previousVersion)
+	}
+
+	log.Printf("[lambda] Canary healthy — promoting version %s to 100%%", newVersion)
+	return promote(ctx, client, cfg, newVersion)
+}
+
+// shiftLinear steps traffic to newVersion by LambdaCanaryPercent every
+// LambdaBakeMinutes, rolling back immediately if an alarm fires at any step.
+func shiftLinear(ctx context.Context, client *lambda.Client, awsCfg aws.Config, cfg *config.Config, previousVersion, newVersion string) error {
+	if cfg.LambdaCanaryPercent <= 0 || cfg.LambdaCanaryPercent > 100 {
+		return fmt.Errorf("invalid LambdaCanaryPercent %g: must be in (0, 100]", cfg.LambdaCanaryPercent)
+	}
+
+	for weight := cfg.LambdaCanaryPercent; weight < 100; weight += cfg.LambdaCanaryPercent {
+		if err := setAliasWeight(ctx, client, cfg, previousVersion, newVersion, weight); err != nil {
+			return err
+		}
+
+		if alarmed, err := bakeAndWatch(ctx, awsCfg, cfg, cfg.LambdaBakeMinutes); err != nil {
+			return err
+		} else if alarmed {
+			log.Printf("[lambda] Alarm triggered at %.0f%% — rolling back to version %s", weight, previousVersion)
+			return RollbackLambda(ctx, awsCfg, cfg, previousVersion)
+		}
+	}
+
+	log.Printf("[lambda] Linear shift complete — promoting version %s to 100%%", newVersion)
+	return promote(ctx, client, cfg, newVersion)
+}
+
+// setAliasWeight points the alias at previousVersion with weight% of traffic
+// diverted to newVersion via AdditionalVersionWeights.
+func setAliasWeight(ctx context.Context, client *lambda.Client, cfg *config.Config, previousVersion, newVersion string, percent float64) error {
+	log.Printf("[lambda] Routing %.0f%% of %s traffic to version %s", percent, cfg.LambdaAliasName, newVersion)
+	_, err := client.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(cfg.LambdaFuncName),
+		Name:            aws.String(cfg.LambdaAliasName),
+		FunctionVersion: aws.String(previousVersion),
+		RoutingConfig: &lambdaTypes.AliasRoutingConfiguration{
+			AdditionalVersionWeights: map[string]float64{
+				newVersion: percent / 100,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateAlias (weight %.0f%%): %w", percent, err)
+	}
+	return nil
+}
+
+// promote points the alias fully at version with no weighted routing.
+func promote(ctx context.Context, client *lambda.Client, cfg *config.Config, version string) error {
+	_, err := client.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(cfg.LambdaFuncName),
+		Name:            aws.String(cfg.LambdaAliasName),
+		FunctionVersion: aws.String(version),
+		RoutingConfig:   &lambdaTypes.AliasRoutingConfiguration{},
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateAlias (promote): %w", err)
+	}
+	return nil
+}
+
+// RollbackLambda points the live alias back at version with no weighted
+// routing, undoing any in-flight canary or linear shift.
+func RollbackLambda(ctx context.Context, awsCfg aws.Config, cfg *config.Config, version string) error {
+	client := lambda.NewFromConfig(awsCfg)
+	log.Printf("[lambda] Rolling back %s to version %s", cfg.LambdaAliasName, version)
+	return promote(ctx, client, cfg, version)
+}
+
+// bakeAndWatch polls the alarms created by SetupCloudWatch every 30s for
+// bakeMinutes, returning true as soon as any of them enters ALARM state.
+func bakeAndWatch(ctx context.Context, awsCfg aws.Config, cfg *config.Config, bakeMinutes int) (bool, error) {
+	client := cloudwatch.NewFromConfig(awsCfg)
+	alarmNames := []string{
+		fmt.Sprintf("%s-errors", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-duration", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-throttles", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-dynamo-throttles", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-processed-records-anomaly", cfg.LambdaFuncName),
+		fmt.Sprintf("%s-degraded", cfg.LambdaFuncName),
+	}
+
+	deadline := time.Now().Add(time.Duration(bakeMinutes) * time.Minute)
+	for time.Now().Before(deadline) {
+		out, err := client.DescribeAlarms(ctx, &cloudwatch.DescribeAlarmsInput{
+			AlarmNames: alarmNames,
+			AlarmTypes: []cwTypes.AlarmType{cwTypes.AlarmTypeMetricAlarm, cwTypes.AlarmTypeCompositeAlarm},
+		})
+		if err != nil {
+			return false, fmt.Errorf("DescribeAlarms: %w", err)
+		}
+		for _, a := range out.MetricAlarms {
+			if a.StateValue == cwTypes.StateValueAlarm {
+				log.Printf("[lambda] Alarm in ALARM state during bake: %s", aws.ToString(a.AlarmName))
+				return true, nil
+			}
+		}
+		for _, a := range out.CompositeAlarms {
+			if a.StateValue == cwTypes.StateValueAlarm {
+				log.Printf("[lambda] Composite alarm in ALARM state during bake: %s", aws.ToString(a.AlarmName))
+				return true, nil
+			}
+		}
+		select {
+		case <-time.After(30 * time.Second):
+		case <-ctx.Done():
+			return false, fmt.Errorf("bake wait: %w", ctx.Err())
+		}
+	}
+	return false, nil
+}
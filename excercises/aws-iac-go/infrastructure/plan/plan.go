@@ -0,0 +1,227 @@
+// Package plan executes a set of provisioning steps as a dependency DAG
+// instead of a fixed sequence, so independent resources (e.g. SNS and
+// DynamoDB) provision concurrently while dependent ones (e.g. CloudWatch)
+// still wait on their inputs.
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/sync/errgroup"
+)
+
+// Node is a single provisioning step in the DAG. Name must be unique within
+// a Graph and is what DependsOn entries refer to. Provision receives a
+// *Outputs shared by the whole run so a node can read values published by
+// the nodes it depends on (e.g. Lambda reading the IAM role ARN).
+type Node struct {
+	Name      string
+	DependsOn []string
+	Provision func(ctx context.Context, out *Outputs) error
+}
+
+// Outputs is a concurrency-safe string key/value store nodes use to pass
+// results (ARNs, IDs) to the nodes that depend on them.
+type Outputs struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewOutputs returns an empty Outputs store.
+func NewOutputs() *Outputs {
+	return &Outputs{values: make(map[string]string)}
+}
+
+// Set records a value under key, overwriting any previous value.
+func (o *Outputs) Set(key, value string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values[key] = value
+}
+
+// Get returns the value recorded under key, or "" if none was set.
+func (o *Outputs) Get(key string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.values[key]
+}
+
+// Graph is a validated, ready-to-run set of Nodes.
+type Graph struct {
+	nodes map[string]Node
+	order []string // insertion order, used for stable plan printing
+}
+
+// NewGraph validates nodes (unique names, known dependencies, no cycles) and
+// returns a Graph ready to Run or print via TopoLevels.
+func NewGraph(nodes []Node) (*Graph, error) {
+	g := &Graph{nodes: make(map[string]Node, len(nodes))}
+	for _, n := range nodes {
+		if _, exists := g.nodes[n.Name]; exists {
+			return nil, fmt.Errorf("duplicate node %q", n.Name)
+		}
+		g.nodes[n.Name] = n
+		g.order = append(g.order, n.Name)
+	}
+	for _, n := range nodes {
+		for _, dep := range n.DependsOn {
+			if _, ok := g.nodes[dep]; !ok {
+				return nil, fmt.Errorf("node %q depends on unknown node %q", n.Name, dep)
+			}
+		}
+	}
+	if _, err := g.TopoLevels(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// TopoLevels groups nodes into levels where every node in a level depends
+// only on nodes in earlier levels — i.e. everything within one level can run
+// concurrently. Returns an error if the graph has a cycle.
+func (g *Graph) TopoLevels() ([][]string, error) {
+	remaining := make(map[string][]string, len(g.nodes))
+	for name, n := range g.nodes {
+		remaining[name] = append([]string(nil), n.DependsOn...)
+	}
+
+	var levels [][]string
+	for len(remaining) > 0 {
+		var level []string
+		for _, name := range g.order {
+			deps, ok := remaining[name]
+			if !ok {
+				continue
+			}
+			if len(deps) == 0 {
+				level = append(level, name)
+			}
+		}
+		if len(level) == 0 {
+			return nil, fmt.Errorf("dependency cycle detected among: %v", remainingNames(remaining))
+		}
+		for _, name := range level {
+			delete(remaining, name)
+		}
+		for name, deps := range remaining {
+			remaining[name] = removeAll(deps, level)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// Run executes the DAG one TopoLevels() level at a time, so every node's
+// DependsOn entries have already finished (or been skipped) by the time it
+// starts — a bounded pool (maxParallel > 0) can never fill up with goroutines
+// that are all waiting on dependencies nothing has scheduled yet. Within a
+// level, up to maxParallel nodes run at once; maxParallel <= 0 means
+// unbounded. Errors from every failed branch are aggregated via multierror
+// instead of the first one masking the rest — a node is skipped (not run)
+// once any of its dependencies has failed.
+func (g *Graph) Run(ctx context.Context, out *Outputs, maxParallel int) error {
+	levels, err := g.TopoLevels()
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var failed multierror.Error
+	failedNodes := make(map[string]bool, len(g.nodes))
+
+	for _, level := range levels {
+		group, gctx := errgroup.WithContext(ctx)
+		if maxParallel > 0 {
+			group.SetLimit(maxParallel)
+		}
+
+		for _, name := range level {
+			n := g.nodes[name]
+			group.Go(func() error {
+				mu.Lock()
+				depFailed := false
+				for _, dep := range n.DependsOn {
+					if failedNodes[dep] {
+						depFailed = true
+						break
+					}
+				}
+				if depFailed {
+					failedNodes[n.Name] = true // propagate so nodes depending on this one are skipped too
+				}
+				mu.Unlock()
+				if depFailed {
+					return nil // dependency failed — skip this node, don't mask its error
+				}
+
+				if err := n.Provision(gctx, out); err != nil {
+					mu.Lock()
+					failed.Errors = append(failed.Errors, fmt.Errorf("%s: %w", n.Name, err))
+					failedNodes[n.Name] = true
+					mu.Unlock()
+					return nil // recorded, not returned — let independent branches keep running
+				}
+				return nil
+			})
+		}
+
+		if err := group.Wait(); err != nil {
+			mu.Lock()
+			failed.Errors = append(failed.Errors, err)
+			mu.Unlock()
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return failed.ErrorOrNil()
+}
+
+func removeAll(deps, done []string) []string {
+	kept := deps[:0:0]
+	for _, d := range deps {
+		skip := false
+		for _, x := range done {
+			if d == x {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+func remainingNames(remaining map[string][]string) []string {
+	names := make([]string, 0, len(remaining))
+	for name := range remaining {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Describe renders the topological levels produced by TopoLevels as
+// human-readable lines for the --plan flag: one line per level, showing
+// which nodes could run concurrently and the overall parallelism estimate.
+func Describe(levels [][]string) string {
+	out := fmt.Sprintf("%d levels, max parallelism %d\n", len(levels), widestLevel(levels))
+	for i, level := range levels {
+		out += fmt.Sprintf("  level %d: %v\n", i+1, level)
+	}
+	return out
+}
+
+func widestLevel(levels [][]string) int {
+	max := 0
+	for _, level := range levels {
+		if len(level) > max {
+			max = len(level)
+		}
+	}
+	return max
+}
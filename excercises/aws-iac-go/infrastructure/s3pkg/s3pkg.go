@@ -0,0 +1,139 @@
+// Package s3pkg manages the S3-backed deployment bucket used to ship large
+// Lambda ZIP artifacts that no longer fit in a CreateFunction/UpdateFunctionCode
+// inline ZipFile payload (50 MB cap).
+package s3pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// multipartThreshold is the size above which uploads go through s3manager's
+// multipart uploader instead of a single PutObject call.
+const multipartThreshold = 5 * 1024 * 1024 // 5 MB
+
+// hashMetadataKey is the S3 object metadata key that stores the SHA256 of the
+// uploaded ZIP, used to skip re-uploading unchanged artifacts.
+const hashMetadataKey = "zip-sha256"
+
+// EnsureBucket creates the deployment bucket if it doesn't already exist,
+// enables versioning, and sets a lifecycle rule expiring non-current versions
+// after 30 days so old deploys don't accumulate storage cost forever.
+func EnsureBucket(ctx context.Context, awsCfg aws.Config, bucket, region string) error {
+	client := s3.NewFromConfig(awsCfg)
+
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err == nil {
+		log.Printf("[s3pkg] Bucket %s already exists", bucket)
+		return ensureBucketConfig(ctx, client, bucket)
+	}
+
+	log.Printf("[s3pkg] Creating deployment bucket: %s", bucket)
+	createInput := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	if region != "us-east-1" {
+		createInput.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(region),
+		}
+	}
+	if _, err := client.CreateBucket(ctx, createInput); err != nil {
+		return fmt.Errorf("CreateBucket: %w", err)
+	}
+
+	return ensureBucketConfig(ctx, client, bucket)
+}
+
+func ensureBucketConfig(ctx context.Context, client *s3.Client, bucket string) error {
+	_, err := client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucket),
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: types.BucketVersioningStatusEnabled,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("PutBucketVersioning: %w", err)
+	}
+
+	_, err = client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+			Rules: []types.LifecycleRule{
+				{
+					ID:     aws.String("expire-old-deploy-artifacts"),
+					Status: types.ExpirationStatusEnabled,
+					Filter: &types.LifecycleRuleFilterMemberPrefix{Value: ""},
+					NoncurrentVersionExpiration: &types.NoncurrentVersionExpiration{
+						NoncurrentDays: aws.Int32(30),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("PutBucketLifecycleConfiguration: %w", err)
+	}
+
+	log.Printf("[s3pkg] Bucket %s ready (versioned, 30-day lifecycle)", bucket)
+	return nil
+}
+
+// UploadResult describes where a ZIP artifact ended up in S3.
+type UploadResult struct {
+	Bucket    string
+	Key       string
+	VersionID string
+	Skipped   bool // true when the object already existed with a matching hash
+}
+
+// Upload puts data at s3://bucket/key, skipping the upload entirely if an
+// object already exists there with a matching SHA256 (stored in object
+// metadata). Uploads larger than 5 MB go through the multipart uploader.
+func Upload(ctx context.Context, awsCfg aws.Config, bucket, key string, data []byte) (*UploadResult, error) {
+	client := s3.NewFromConfig(awsCfg)
+	hash := sha256.Sum256(data)
+	hexHash := hex.EncodeToString(hash[:])
+
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil && head.Metadata[hashMetadataKey] == hexHash {
+		log.Printf("[s3pkg] s3://%s/%s already matches SHA256 %s — skipping upload", bucket, key, hexHash[:12])
+		return &UploadResult{Bucket: bucket, Key: key, VersionID: aws.ToString(head.VersionId), Skipped: true}, nil
+	}
+
+	log.Printf("[s3pkg] Uploading %d bytes to s3://%s/%s (sha256 %s)", len(data), bucket, key, hexHash[:12])
+
+	if len(data) > multipartThreshold {
+		uploader := manager.NewUploader(client)
+		out, err := uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			Body:     bytes.NewReader(data),
+			Metadata: map[string]string{hashMetadataKey: hexHash},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("multipart upload: %w", err)
+		}
+		return &UploadResult{Bucket: bucket, Key: key, VersionID: aws.ToString(out.VersionID)}, nil
+	}
+
+	out, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		Body:     bytes.NewReader(data),
+		Metadata: map[string]string{hashMetadataKey: hexHash},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PutObject: %w", err)
+	}
+	return &UploadResult{Bucket: bucket, Key: key, VersionID: aws.ToString(out.VersionId)}, nil
+}
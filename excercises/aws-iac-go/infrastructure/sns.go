@@ -3,10 +3,11 @@ package infrastructure
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"aws-iac-go/config"
+	"aws-iac-go/logging"
+	"aws-iac-go/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
@@ -17,25 +18,31 @@ import (
 // Returns the topic ARN. CreateTopic is idempotent — safe to call multiple times.
 func SetupSNS(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (string, error) {
 	client := sns.NewFromConfig(awsCfg)
+	entry := logging.Step(ctx, "sns", cfg.SNSTopicName)
 
 	// CreateTopic is idempotent — returns the existing ARN if the topic already exists
-	log.Printf("[sns] Creating/verifying topic: %s", cfg.SNSTopicName)
-	out, err := client.CreateTopic(ctx, &sns.CreateTopicInput{
-		Name: aws.String(cfg.SNSTopicName),
-		Tags: []snsTypes.Tag{
-			{Key: aws.String("Project"),   Value: aws.String("iac-go")},
-			{Key: aws.String("ManagedBy"), Value: aws.String("go-sdk")},
-		},
+	entry.Infof("Creating/verifying topic: %s", cfg.SNSTopicName)
+	var out *sns.CreateTopicOutput
+	err := utils.RetryWithBackoff(ctx, cfg.MaxRetries, func() error {
+		var createErr error
+		out, createErr = client.CreateTopic(ctx, &sns.CreateTopicInput{
+			Name: aws.String(cfg.SNSTopicName),
+			Tags: []snsTypes.Tag{
+				{Key: aws.String("Project"), Value: aws.String("iac-go")},
+				{Key: aws.String("ManagedBy"), Value: aws.String("go-sdk")},
+			},
+		})
+		return createErr
 	})
 	if err != nil {
 		return "", fmt.Errorf("CreateTopic: %w", err)
 	}
 	topicARN := aws.ToString(out.TopicArn)
-	log.Printf("[sns] Topic ready: %s", topicARN)
+	entry.Infof("Topic ready: %s", topicARN)
 
 	// Optional email subscription
 	if cfg.AlertEmail != "" {
-		log.Printf("[sns] Adding email subscription: %s", cfg.AlertEmail)
+		entry.Infof("Adding email subscription: %s", cfg.AlertEmail)
 		_, err = client.Subscribe(ctx, &sns.SubscribeInput{
 			TopicArn: aws.String(topicARN),
 			Protocol: aws.String("email"),
@@ -43,9 +50,9 @@ func SetupSNS(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (strin
 		})
 		if err != nil {
 			// Non-fatal — email subscription is optional
-			log.Printf("[sns] WARN: Could not subscribe email: %v", err)
+			entry.WithField("error", err).Warn("Could not subscribe email")
 		} else {
-			log.Printf("[sns] Email subscription added (confirmation required)")
+			entry.Info("Email subscription added (confirmation required)")
 		}
 	}
 
@@ -55,12 +62,13 @@ func SetupSNS(ctx context.Context, awsCfg aws.Config, cfg *config.Config) (strin
 // DeleteSNSTopic removes the SNS topic (cleanup helper).
 func DeleteSNSTopic(ctx context.Context, awsCfg aws.Config, topicARN string) error {
 	client := sns.NewFromConfig(awsCfg)
+	entry := logging.Step(ctx, "sns", topicARN)
 	_, err := client.DeleteTopic(ctx, &sns.DeleteTopicInput{
 		TopicArn: aws.String(topicARN),
 	})
 	if err != nil && !strings.Contains(err.Error(), "NotFound") {
 		return fmt.Errorf("DeleteTopic: %w", err)
 	}
-	log.Printf("[sns] Topic %s deleted", topicARN)
+	entry.Infof("Topic %s deleted", topicARN)
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// NewBackend selects a Backend implementation based on cfg.StateBackend:
+// "local" (default), "s3", or "ssm".
+func NewBackend(awsCfg aws.Config, backendKind, localPath, s3Bucket, s3Key, ssmParamName string) (Backend, error) {
+	switch backendKind {
+	case "", "local":
+		return NewLocalFileBackend(localPath), nil
+	case "s3":
+		if s3Bucket == "" {
+			return nil, fmt.Errorf("state backend %q requires a bucket name", backendKind)
+		}
+		return NewS3Backend(awsCfg, s3Bucket, s3Key), nil
+	case "ssm":
+		if ssmParamName == "" {
+			return nil, fmt.Errorf("state backend %q requires a parameter name", backendKind)
+		}
+		return NewSSMBackend(awsCfg, ssmParamName), nil
+	default:
+		return nil, fmt.Errorf("unknown state backend: %q (want local, s3, or ssm)", backendKind)
+	}
+}
@@ -0,0 +1,140 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+)
+
+// s3Backend stores state as a single versioned object in an S3 bucket.
+// Object versioning gives a free history of every deploy's state without
+// any extra bookkeeping.
+type s3Backend struct {
+	client *s3.Client
+	bucket string
+	key    string
+}
+
+// NewS3Backend returns a Backend that reads/writes state as a JSON object at
+// s3://bucket/key. The bucket is expected to already exist with versioning
+// enabled; this backend does not create or configure it.
+func NewS3Backend(awsCfg aws.Config, bucket, key string) Backend {
+	return &s3Backend{
+		client: s3.NewFromConfig(awsCfg),
+		bucket: bucket,
+		key:    key,
+	}
+}
+
+func (b *s3Backend) Load(ctx context.Context) (*State, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key),
+	})
+	if err != nil {
+		var nsk *s3Types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return &State{Version: 1}, nil
+		}
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey" {
+			return &State{Version: 1}, nil
+		}
+		return nil, fmt.Errorf("GetObject s3://%s/%s: %w", b.bucket, b.key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read state object body: %w", err)
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("unmarshal state object: %w", err)
+	}
+	return &s, nil
+}
+
+func (b *s3Backend) Save(ctx context.Context, s *State) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	_, err = b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(b.key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("PutObject s3://%s/%s: %w", b.bucket, b.key, err)
+	}
+	log.Printf("[state] Saved %d resources to s3://%s/%s", len(s.Resources), b.bucket, b.key)
+	return nil
+}
+
+// ssmBackend stores state as a single SSM Parameter Store parameter. Suited
+// to small deployments where a bucket feels like overkill.
+type ssmBackend struct {
+	client *ssm.Client
+	name   string
+}
+
+// NewSSMBackend returns a Backend that reads/writes state as a JSON blob in
+// the SSM Parameter Store parameter named name.
+func NewSSMBackend(awsCfg aws.Config, name string) Backend {
+	return &ssmBackend{
+		client: ssm.NewFromConfig(awsCfg),
+		name:   name,
+	}
+}
+
+func (b *ssmBackend) Load(ctx context.Context) (*State, error) {
+	out, err := b.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name: aws.String(b.name),
+	})
+	if err != nil {
+		var notFound *ssmTypes.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return &State{Version: 1}, nil
+		}
+		return nil, fmt.Errorf("GetParameter %s: %w", b.name, err)
+	}
+	var s State
+	if err := json.Unmarshal([]byte(aws.ToString(out.Parameter.Value)), &s); err != nil {
+		return nil, fmt.Errorf("unmarshal state parameter %s: %w", b.name, err)
+	}
+	return &s, nil
+}
+
+func (b *ssmBackend) Save(ctx context.Context, s *State) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	_, err = b.client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(b.name),
+		Value:     aws.String(string(data)),
+		Type:      ssmTypes.ParameterTypeString,
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("PutParameter %s: %w", b.name, err)
+	}
+	log.Printf("[state] Saved %d resources to SSM parameter %s", len(s.Resources), b.name)
+	return nil
+}
@@ -0,0 +1,127 @@
+// Package state tracks the resources provisioned by the infrastructure package
+// so that destroy can target exactly what was created, instead of guessing
+// ARNs from naming conventions.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Resource is a single provisioned item recorded by a Setup* function.
+type Resource struct {
+	Type       string            `json:"type"` // e.g. "sns-topic", "iam-role", "lambda-function"
+	Name       string            `json:"name"`
+	ARN        string            `json:"arn"`
+	PhysicalID string            `json:"physical_id,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Hash       string            `json:"hash,omitempty"` // content hash (e.g. Lambda ZIP SHA256)
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// State is the full set of resources provisioned by a single deployment.
+type State struct {
+	Version   int        `json:"version"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Resources []Resource `json:"resources"`
+}
+
+// Backend persists and retrieves a State. Implementations: local JSON file,
+// S3 object (versioned), SSM Parameter Store.
+type Backend interface {
+	Load(ctx context.Context) (*State, error)
+	Save(ctx context.Context, s *State) error
+}
+
+// Put records or replaces a resource by (Type, Name).
+func (s *State) Put(r Resource) {
+	r.CreatedAt = time.Now()
+	for i, existing := range s.Resources {
+		if existing.Type == r.Type && existing.Name == r.Name {
+			s.Resources[i] = r
+			return
+		}
+	}
+	s.Resources = append(s.Resources, r)
+}
+
+// Get returns the resource matching (Type, Name), if recorded.
+func (s *State) Get(resType, name string) (Resource, bool) {
+	for _, r := range s.Resources {
+		if r.Type == resType && r.Name == name {
+			return r, true
+		}
+	}
+	return Resource{}, false
+}
+
+// ReverseDependencyOrder returns the recorded resources in the reverse of the
+// order they were added, so destroy walks down the dependency chain that
+// deploy walked up.
+func (s *State) ReverseDependencyOrder() []Resource {
+	ordered := make([]Resource, len(s.Resources))
+	for i, r := range s.Resources {
+		ordered[len(s.Resources)-1-i] = r
+	}
+	return ordered
+}
+
+// DetectDrift compares the recorded state against a set of resources observed
+// live in AWS (Type+Name -> ARN) and logs a warning for anything recorded
+// that is now missing or whose ARN changed, without failing the run.
+func (s *State) DetectDrift(observed map[string]string) {
+	for _, r := range s.Resources {
+		key := r.Type + "/" + r.Name
+		liveARN, ok := observed[key]
+		if !ok {
+			log.Printf("[state] WARN: drift detected — %s %q was recorded but no longer exists", r.Type, r.Name)
+			continue
+		}
+		if liveARN != r.ARN {
+			log.Printf("[state] WARN: drift detected — %s %q ARN changed: recorded=%s live=%s",
+				r.Type, r.Name, r.ARN, liveARN)
+		}
+	}
+}
+
+// localFileBackend stores state as a JSON file on disk.
+type localFileBackend struct {
+	path string
+}
+
+// NewLocalFileBackend returns a Backend that reads/writes state as JSON at path.
+func NewLocalFileBackend(path string) Backend {
+	return &localFileBackend{path: path}
+}
+
+func (b *localFileBackend) Load(ctx context.Context) (*State, error) {
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return &State{Version: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state file %s: %w", b.path, err)
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("unmarshal state file %s: %w", b.path, err)
+	}
+	return &s, nil
+}
+
+func (b *localFileBackend) Save(ctx context.Context, s *State) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	if err := os.WriteFile(b.path, data, 0o644); err != nil {
+		return fmt.Errorf("write state file %s: %w", b.path, err)
+	}
+	log.Printf("[state] Saved %d resources to %s", len(s.Resources), b.path)
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package logging provides a structured, correlation-ID-aware logger built on
+// log/slog, shared across the config and infrastructure packages so a single
+// deployment's log lines can be filtered and traced end-to-end.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type ctxKey struct{}
+
+// New builds a *slog.Logger that emits JSON when LOG_FORMAT=json, plain text
+// otherwise, at the level named by LOG_LEVEL (debug/info/warn/error; defaults
+// to info on an unset or unrecognized value).
+func New() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(os.Getenv("LOG_LEVEL"))}
+
+	var handler slog.Handler
+	if os.Getenv("LOG_FORMAT") == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	return slog.New(handler)
+}
+
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Entry pairs a *slog.Logger already tagged with correlation fields with the
+// context it was derived from, so every Info/Warn/Error call can go through
+// slog's *Context variants without each call site threading ctx separately.
+type Entry struct {
+	ctx    context.Context
+	logger *slog.Logger
+}
+
+// NewDeployment returns a root *Entry tagged with a fresh deployment_id,
+// along with that id, identifying every log line that a single `go run .`
+// invocation writes through this entry or entries derived from it.
+func NewDeployment(logger *slog.Logger) (*Entry, string) {
+	id := uuid.NewString()
+	return &Entry{ctx: context.Background(), logger: logger.With("deployment_id", id)}, id
+}
+
+// WithContext attaches entry to ctx so it can be recovered deeper in the call
+// stack without threading a logger parameter through every function.
+func WithContext(ctx context.Context, entry *Entry) context.Context {
+	entry.ctx = ctx
+	return context.WithValue(ctx, ctxKey{}, entry)
+}
+
+// FromContext recovers the entry attached by WithContext, or a bare entry on
+// a fresh New() logger if none was attached (e.g. in tests).
+func FromContext(ctx context.Context) *Entry {
+	if entry, ok := ctx.Value(ctxKey{}).(*Entry); ok {
+		return entry
+	}
+	return &Entry{ctx: ctx, logger: New()}
+}
+
+// Step returns a child entry tagged with the given component and resource,
+// for use within a single Setup* call (e.g. component="iam", resource="iac-lambda-role").
+func Step(ctx context.Context, component, resource string) *Entry {
+	parent := FromContext(ctx)
+	return &Entry{ctx: ctx, logger: parent.logger.With("component", component, "resource", resource)}
+}
+
+// WithField returns a child entry carrying one additional attribute.
+func (e *Entry) WithField(key string, value any) *Entry {
+	return &Entry{ctx: e.ctx, logger: e.logger.With(key, value)}
+}
+
+func (e *Entry) Info(msg string)  { e.logger.InfoContext(e.ctx, msg) }
+func (e *Entry) Warn(msg string)  { e.logger.WarnContext(e.ctx, msg) }
+func (e *Entry) Error(msg string) { e.logger.ErrorContext(e.ctx, msg) }
+
+func (e *Entry) Infof(format string, args ...any)  { e.logger.InfoContext(e.ctx, fmt.Sprintf(format, args...)) }
+func (e *Entry) Warnf(format string, args ...any)  { e.logger.WarnContext(e.ctx, fmt.Sprintf(format, args...)) }
+func (e *Entry) Errorf(format string, args ...any) { e.logger.ErrorContext(e.ctx, fmt.Sprintf(format, args...)) }
+
+// Fatalf logs at error level and terminates the process, for use at the top
+// of main where an error leaves nothing left to do but exit.
+func (e *Entry) Fatalf(format string, args ...any) {
+	e.logger.ErrorContext(e.ctx, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
@@ -5,10 +5,14 @@ import (
 	"flag"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"aws-iac-go/config"
 	"aws-iac-go/infrastructure"
+	"aws-iac-go/infrastructure/plan"
+	"aws-iac-go/infrastructure/state"
+	"aws-iac-go/logging"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -18,6 +22,10 @@ func main() {
 	// ── CLI flags ──────────────────────────────────────────────────────────
 	destroy := flag.Bool("destroy", false, "Tear down all provisioned infrastructure")
 	testRun := flag.Bool("test", false,   "Invoke the Lambda function after deployment")
+	shift := flag.String("shift", "", "Deploy a new Lambda version with traffic shifting: 'canary' or 'linear'")
+	packageOnly := flag.Bool("package-only", false, "Build and upload the Lambda ZIP to S3 without deploying")
+	showPlan := flag.Bool("plan", false, "Print the deployment DAG's topological order and parallelism estimate without provisioning anything")
+	maxParallel := flag.Int("max-parallel", 0, "Maximum number of DAG nodes to provision concurrently (0 = unbounded)")
 	flag.Parse()
 
 	log.SetFlags(log.LstdFlags | log.Lmsgprefix)
@@ -27,16 +35,30 @@ func main() {
 
 	// ── Load configuration ─────────────────────────────────────────────────
 	cfg := config.Load()
-	ctx := context.Background()
+
+	// Tag every log line written through a logging.Entry in this run with the
+	// same deployment_id so CI and log aggregation can trace a single
+	// `go run .` end-to-end. Lines written with the standard log package (the
+	// banner, the summary, and the state, s3pkg, dynamostore and traffic-shift
+	// code) and config.Load's own lines do not carry it.
+	deployEntry, deploymentID := logging.NewDeployment(logging.New())
+	ctx := logging.WithContext(context.Background(), deployEntry)
+	entry := logging.Step(ctx, "main", "")
+	entry.Infof("deployment_id: %s", deploymentID)
 
 	// ── AWS SDK config (reads env vars / ~/.aws/credentials automatically) ──
-	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
+	loadOpts := []func(*awsconfig.LoadOptions) error{
 		awsconfig.WithRegion(cfg.AWSRegion),
-	)
+	}
+	if resolver := infrastructure.GlobalEndpointResolver(cfg); resolver != nil {
+		loadOpts = append(loadOpts, awsconfig.WithEndpointResolverWithOptions(resolver))
+		entry.Infof("Routing all AWS services to LocalStack: %s", cfg.LocalstackEndpoint)
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
 	if err != nil {
-		log.Fatalf("Failed to load AWS config: %v", err)
+		entry.Fatalf("Failed to load AWS config: %v", err)
 	}
-	log.Printf("[main] AWS region: %s", cfg.AWSRegion)
+	entry.Infof("AWS region: %s", cfg.AWSRegion)
 
 	// ── Destroy mode ───────────────────────────────────────────────────────
 	if *destroy {
@@ -44,86 +66,218 @@ func main() {
 		return
 	}
 
+	// ── Package-only mode (CI artifact build) ───────────────────────────────
+	if *packageOnly {
+		result, err := infrastructure.PackageOnly(ctx, awsCfg, cfg)
+		if err != nil {
+			entry.Fatalf("FATAL during package-only build: %v", err)
+		}
+		entry.Infof("Packaged: s3://%s/%s (version %s)", result.Bucket, result.Key, result.VersionID)
+		return
+	}
+
+	// ── Traffic-shifting deploy mode ───────────────────────────────────────
+	if *shift != "" {
+		routing := infrastructure.RoutingConfig{Strategy: infrastructure.RoutingStrategy(*shift)}
+		if err := infrastructure.DeployLambdaWithShift(ctx, awsCfg, cfg, routing); err != nil {
+			entry.Fatalf("FATAL during shifted deploy: %v", err)
+		}
+		return
+	}
+
+	// ── Plan mode — print the DAG without provisioning anything ─────────────
+	if *showPlan {
+		graph, err := buildDeployGraph(awsCfg, cfg, &state.State{}, nil, plan.NewOutputs())
+		if err != nil {
+			entry.Fatalf("FATAL building deployment plan: %v", err)
+		}
+		levels, err := graph.TopoLevels()
+		if err != nil {
+			entry.Fatalf("FATAL computing plan: %v", err)
+		}
+		entry.Info("Deployment plan:\n" + plan.Describe(levels))
+		return
+	}
+
 	// ── Deploy mode ────────────────────────────────────────────────────────
-	runDeploy(ctx, awsCfg, cfg, *testRun)
+	runDeploy(ctx, awsCfg, cfg, *testRun, *maxParallel)
 }
 
-// runDeploy provisions all infrastructure in dependency order.
-func runDeploy(ctx context.Context, awsCfg aws.Config, cfg *config.Config, runTest bool) {
+// runDeploy provisions all infrastructure by running the deployment DAG:
+// SNS, IAM and DynamoDB fan out in parallel, Lambda waits on IAM, and
+// CloudWatch waits on Lambda and SNS. maxParallel <= 0 means unbounded.
+func runDeploy(ctx context.Context, awsCfg aws.Config, cfg *config.Config, runTest bool, maxParallel int) {
 	start := time.Now()
-	log.Println("[main] ══════════════════════════════════════")
-	log.Println("[main]   DEPLOYMENT STARTED                 ")
-	log.Println("[main] ══════════════════════════════════════")
-
-	// Step 1: SNS — needed early so alarms have an action target
-	log.Println("[main] [1/5] Setting up SNS...")
-	snsARN, err := infrastructure.SetupSNS(ctx, awsCfg, cfg)
-	mustSucceed(err, "SNS")
-
-	// Step 2: IAM — must exist before Lambda can be created
-	log.Println("[main] [2/5] Setting up IAM...")
-	roleARN, err := infrastructure.SetupIAM(ctx, awsCfg, cfg)
-	mustSucceed(err, "IAM")
-
-	// Step 3: DynamoDB — independent of Lambda, could run in parallel in future
-	log.Println("[main] [3/5] Setting up DynamoDB...")
-	_, err = infrastructure.SetupDynamoDB(ctx, awsCfg, cfg)
-	mustSucceed(err, "DynamoDB")
-
-	// Step 4: Lambda — requires IAM role ARN and table name
-	log.Println("[main] [4/5] Deploying Lambda function...")
-	funcARN, err := infrastructure.SetupLambda(ctx, awsCfg, cfg, roleARN)
-	mustSucceed(err, "Lambda")
-
-	// Step 5: CloudWatch — requires function ARN and SNS topic ARN
-	log.Println("[main] [5/5] Setting up CloudWatch...")
-	err = infrastructure.SetupCloudWatch(ctx, awsCfg, cfg, funcARN, snsARN)
-	mustSucceed(err, "CloudWatch")
+	entry := logging.Step(ctx, "main", "")
+	entry.Info("DEPLOYMENT STARTED")
+
+	stateBackend, err := state.NewBackend(awsCfg, cfg.StateBackend, cfg.StateFilePath,
+		cfg.StateS3Bucket, cfg.StateS3Key, cfg.StateSSMParam)
+	mustSucceed(ctx, err, "state backend")
+	deployState, err := stateBackend.Load(ctx)
+	mustSucceed(ctx, err, "state load")
+
+	out := plan.NewOutputs()
+	graph, err := buildDeployGraph(awsCfg, cfg, deployState, stateBackend, out)
+	mustSucceed(ctx, err, "build deployment graph")
+
+	mustSucceed(ctx, graph.Run(ctx, out, maxParallel), "deployment")
+
+	funcARN := out.Get("lambda-function")
+	snsARN := out.Get("sns-topic")
 
 	// ── Health checks ──────────────────────────────────────────────────────
-	log.Println("[main] ── Health checks ──")
+	entry.Info("── Health checks ──")
 	if err := infrastructure.DynamoDBHealthCheck(ctx, awsCfg, cfg); err != nil {
-		log.Printf("[main] WARN: DynamoDB health check failed: %v", err)
+		entry.WithField("error", err).Warn("DynamoDB health check failed")
 	}
 
 	// ── Optional test invocation ───────────────────────────────────────────
 	if runTest {
-		log.Println("[main] ── Test invocation ──")
+		entry.Info("── Test invocation ──")
 		if err := infrastructure.InvokeLambdaTest(ctx, awsCfg, cfg); err != nil {
-			log.Printf("[main] WARN: Lambda test failed: %v", err)
+			entry.WithField("error", err).Warn("Lambda test failed")
 		}
 	}
 
 	// ── Summary ────────────────────────────────────────────────────────────
 	elapsed := time.Since(start).Round(time.Second)
-	log.Println("[main] ══════════════════════════════════════")
-	log.Printf("[main]   ✅ DEPLOYMENT COMPLETE (%s)         ", elapsed)
-	log.Println("[main] ══════════════════════════════════════")
+	entry.WithField("elapsed", elapsed.String()).Info("✅ DEPLOYMENT COMPLETE")
 	printSummary(cfg, funcARN, snsARN)
 }
 
+// buildDeployGraph declares the five provisioning steps as a plan.Graph:
+// SNS, IAM and DynamoDB have no dependencies between them and fan out in
+// parallel; Lambda depends on IAM (it needs the role ARN); CloudWatch
+// depends on both Lambda (function ARN) and SNS (alarm action target).
+// stateBackend may be nil (used by --plan, which never calls Provision).
+func buildDeployGraph(awsCfg aws.Config, cfg *config.Config, deployState *state.State,
+	stateBackend state.Backend, out *plan.Outputs) (*plan.Graph, error) {
+
+	var stateMu sync.Mutex
+	record := func(ctx context.Context, r state.Resource) error {
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		deployState.Put(r)
+		return stateBackend.Save(ctx, deployState)
+	}
+
+	return plan.NewGraph([]plan.Node{
+		{
+			Name: "sns-topic",
+			Provision: func(ctx context.Context, out *plan.Outputs) error {
+				snsARN, err := infrastructure.SetupSNS(ctx, awsCfg, cfg)
+				if err != nil {
+					return err
+				}
+				out.Set("sns-topic", snsARN)
+				return record(ctx, state.Resource{Type: "sns-topic", Name: cfg.SNSTopicName, ARN: snsARN})
+			},
+		},
+		{
+			Name: "iam-role",
+			Provision: func(ctx context.Context, out *plan.Outputs) error {
+				roleARN, err := infrastructure.SetupIAM(ctx, awsCfg, cfg)
+				if err != nil {
+					return err
+				}
+				out.Set("iam-role", roleARN)
+				return record(ctx, state.Resource{Type: "iam-role", Name: cfg.LambdaRoleName, ARN: roleARN})
+			},
+		},
+		{
+			Name: "dynamodb-table",
+			Provision: func(ctx context.Context, out *plan.Outputs) error {
+				tableARN, err := infrastructure.SetupDynamoDB(ctx, awsCfg, cfg)
+				if err != nil {
+					return err
+				}
+				out.Set("dynamodb-table", tableARN)
+				return record(ctx, state.Resource{Type: "dynamodb-table", Name: cfg.DynamoTableName, ARN: tableARN})
+			},
+		},
+		{
+			Name:      "lambda-function",
+			DependsOn: []string{"iam-role"},
+			Provision: func(ctx context.Context, out *plan.Outputs) error {
+				funcARN, err := infrastructure.SetupLambda(ctx, awsCfg, cfg, out.Get("iam-role"))
+				if err != nil {
+					return err
+				}
+				out.Set("lambda-function", funcARN)
+				return record(ctx, state.Resource{Type: "lambda-function", Name: cfg.LambdaFuncName, ARN: funcARN})
+			},
+		},
+		{
+			Name:      "cloudwatch",
+			DependsOn: []string{"lambda-function", "sns-topic"},
+			Provision: func(ctx context.Context, out *plan.Outputs) error {
+				err := infrastructure.SetupCloudWatch(ctx, awsCfg, cfg, out.Get("lambda-function"), out.Get("sns-topic"))
+				if err != nil {
+					return err
+				}
+				return record(ctx, state.Resource{Type: "cloudwatch", Name: cfg.LambdaFuncName})
+			},
+		},
+	})
+}
+
 // runDestroy tears down all provisioned resources in reverse dependency order.
 func runDestroy(ctx context.Context, awsCfg aws.Config, cfg *config.Config) {
-	log.Println("[main] ══════════════════════════════════════")
-	log.Println("[main]   DESTROY: removing infrastructure    ")
-	log.Println("[main] ══════════════════════════════════════")
+	entry := logging.Step(ctx, "main", "")
+	entry.Info("DESTROY: removing infrastructure")
+
+	stateBackend, err := state.NewBackend(awsCfg, cfg.StateBackend, cfg.StateFilePath,
+		cfg.StateS3Bucket, cfg.StateS3Key, cfg.StateSSMParam)
+	mustSucceed(ctx, err, "state backend")
+	deployState, err := stateBackend.Load(ctx)
+	mustSucceed(ctx, err, "state load")
 
-	infrastructure.DeleteCloudWatchResources(ctx, awsCfg, cfg)
-	infrastructure.DeleteLambdaFunction(ctx, awsCfg, cfg)
+	if len(deployState.Resources) == 0 {
+		entry.Warn("no recorded state found — falling back to name-based teardown")
+	} else {
+		// Detect drift (resources deleted or recreated out-of-band) before
+		// tearing anything down, so an operator sees a warning instead of a
+		// silent no-op delete.
+		observed := infrastructure.ObserveResources(ctx, awsCfg, cfg, deployState.Resources)
+		deployState.DetectDrift(observed)
+	}
 
-	// SNS ARN is reconstructed from the topic name (simplification).
-	// In production, store ARNs in a state file or SSM Parameter Store.
-	log.Println("[main] Removing SNS, IAM and DynamoDB resources...")
-	infrastructure.DeleteIAMRole(ctx, awsCfg, cfg)     //nolint
-	infrastructure.DeleteDynamoTable(ctx, awsCfg, cfg) //nolint
+	// Walk the recorded resources in reverse dependency order so teardown
+	// targets exactly what was created, instead of guessing ARNs from names.
+	for _, r := range deployState.ReverseDependencyOrder() {
+		entry.Infof("Removing %s: %s", r.Type, r.Name)
+		switch r.Type {
+		case "cloudwatch":
+			infrastructure.DeleteCloudWatchResources(ctx, awsCfg, cfg)
+		case "lambda-function":
+			infrastructure.DeleteLambdaFunction(ctx, awsCfg, cfg) //nolint
+		case "dynamodb-table":
+			infrastructure.DeleteDynamoTable(ctx, awsCfg, cfg) //nolint
+		case "iam-role":
+			infrastructure.DeleteIAMRole(ctx, awsCfg, cfg) //nolint
+		case "sns-topic":
+			infrastructure.DeleteSNSTopic(ctx, awsCfg, r.ARN) //nolint
+		}
+	}
+
+	// Fall back to the old best-effort teardown when no state was recorded
+	// (e.g. infrastructure provisioned before the state subsystem existed).
+	if len(deployState.Resources) == 0 {
+		infrastructure.DeleteCloudWatchResources(ctx, awsCfg, cfg)
+		infrastructure.DeleteLambdaFunction(ctx, awsCfg, cfg)
+		infrastructure.DeleteIAMRole(ctx, awsCfg, cfg)     //nolint
+		infrastructure.DeleteDynamoTable(ctx, awsCfg, cfg) //nolint
+	}
 
-	log.Println("[main] ✅ Infrastructure removed")
+	mustSucceed(ctx, stateBackend.Save(ctx, &state.State{}), "state save")
+	entry.Info("✅ Infrastructure removed")
 }
 
 // mustSucceed terminates the program with a fatal error if err is non-nil.
-func mustSucceed(err error, step string) {
+func mustSucceed(ctx context.Context, err error, step string) {
 	if err != nil {
-		log.Fatalf("[main] FATAL at step '%s': %v", step, err)
+		logging.FromContext(ctx).Fatalf("FATAL at step '%s': %v", step, err)
 		os.Exit(1)
 	}
 }
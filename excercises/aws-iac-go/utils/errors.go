@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+)
+
+// RetryableError wraps an error that is safe to retry (throttling, transient
+// AWS service errors, etc.).
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// PermanentError wraps an error that will never succeed on retry
+// (AccessDenied, ValidationException, a resource that doesn't exist).
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// retryableAWSCodes are smithy API error codes worth retrying: throttling and
+// quota errors, plus IAM's eventual-consistency window right after a role or
+// policy is created.
+var retryableAWSCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"TooManyRequestsException":               true,
+	"RequestLimitExceeded":                   true,
+	"InvalidParameterValueException":         true, // IAM role/policy propagation lag
+	"ProvisionedThroughputExceededException": true,
+	"LimitExceededException":                 true,
+}
+
+// permanentAWSCodes are smithy API error codes that will never succeed on
+// retry and should short-circuit immediately.
+var permanentAWSCodes = map[string]bool{
+	"AccessDenied":              true,
+	"AccessDeniedException":     true,
+	"ValidationException":       true,
+	"ResourceNotFoundException": true,
+}
+
+// ClassifyAWSError inspects err for a smithy.APIError and wraps it as
+// RetryableError or PermanentError based on its ErrorCode(). Errors that
+// aren't a recognized smithy.APIError (e.g. network errors) are treated as
+// retryable, matching the old "retry everything" behavior.
+func ClassifyAWSError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return &RetryableError{Err: err}
+	}
+
+	code := apiErr.ErrorCode()
+	if permanentAWSCodes[code] {
+		return &PermanentError{Err: err}
+	}
+	if retryableAWSCodes[code] {
+		return &RetryableError{Err: err}
+	}
+
+	// Unrecognized AWS error codes default to retryable — better to waste a
+	// few attempts than to give up on something that might self-heal.
+	return &RetryableError{Err: err}
+}
+
+// IsPermanent reports whether err (or anything it wraps) is a PermanentError.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"aws-iac-go/logging"
+
+	"github.com/aws/smithy-go"
+)
+
+// throttlingAWSCodes are smithy API error codes that mean "slow down", where
+// a short fixed sleep is enough to clear a CI-storm-induced burst.
+var throttlingAWSCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"TooManyRequestsException":               true,
+	"RequestLimitExceeded":                   true,
+	"ProvisionedThroughputExceededException": true,
+}
+
+const (
+	throttleSleep      = 5 * time.Second
+	limitExceededSleep = 10 * time.Second
+)
+
+// RetryOnAWSThrottle executes op up to maxAttempts times, sleeping between
+// attempts according to the smithy.APIError code it returns: a fixed 5s for
+// throttling/quota codes, a fixed 10s for LimitExceededException (control-plane
+// limits typically take longer to clear than request throttling), and
+// full-jitter exponential backoff for InternalServerError. Any other error —
+// including one that isn't a recognized smithy.APIError — is returned
+// immediately without retrying. Logs through the *logging.Entry attached to ctx.
+func RetryOnAWSThrottle(ctx context.Context, maxAttempts int, op func() error) error {
+	entry := logging.FromContext(ctx)
+	var lastErr error
+	for i := 0; i < maxAttempts; i++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) {
+			return err
+		}
+
+		var wait time.Duration
+		switch {
+		case throttlingAWSCodes[apiErr.ErrorCode()]:
+			wait = throttleSleep
+		case apiErr.ErrorCode() == "LimitExceededException":
+			wait = limitExceededSleep
+		case apiErr.ErrorCode() == "InternalServerError" || apiErr.ErrorCode() == "InternalFailure":
+			wait = fullJitterBackoff(i)
+		default:
+			return err
+		}
+
+		entry.Warnf("[retry] attempt %d/%d hit %s — retrying in %s", i+1, maxAttempts, apiErr.ErrorCode(), wait)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("retry aborted: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+	return fmt.Errorf("all %d attempts failed: %w", maxAttempts, lastErr)
+}
@@ -3,31 +3,68 @@ package utils
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"fmt"
-	"log"
+	"math/rand"
 	"time"
+
+	"aws-iac-go/logging"
+)
+
+// backoffBase and backoffCap bound the full-jitter exponential backoff used
+// by RetryWithBackoff: sleep = rand(0, min(backoffCap, backoffBase*2^i)).
+const (
+	backoffBase = 1 * time.Second
+	backoffCap  = 30 * time.Second
 )
 
-// RetryWithBackoff executes fn up to attempts times with exponential backoff.
-// Stops and returns nil as soon as fn succeeds.
-func RetryWithBackoff(attempts int, fn func() error) error {
+// RetryWithBackoff executes fn up to attempts times with full-jitter
+// exponential backoff, classifying each error via utils.ClassifyAWSError so
+// permanent AWS errors (AccessDenied, ValidationException, ...) short-circuit
+// immediately instead of wasting the full attempt budget. Aborts as soon as
+// ctx is cancelled. Logs through the *logging.Entry attached to ctx.
+func RetryWithBackoff(ctx context.Context, attempts int, fn func() error) error {
+	entry := logging.FromContext(ctx)
 	var lastErr error
 	for i := 0; i < attempts; i++ {
-		if err := fn(); err == nil {
+		err := fn()
+		if err == nil {
 			return nil
-		} else {
-			lastErr = err
-			wait := time.Duration(1<<uint(i)) * time.Second
-			log.Printf("[retry] attempt %d/%d failed: %v — retrying in %s", i+1, attempts, err, wait)
-			time.Sleep(wait)
+		}
+
+		classified := ClassifyAWSError(err)
+		if IsPermanent(classified) {
+			return fmt.Errorf("permanent error, not retrying: %w", err)
+		}
+		lastErr = err
+
+		wait := fullJitterBackoff(i)
+		entry.Warnf("[retry] attempt %d/%d failed: %v — retrying in %s", i+1, attempts, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("retry aborted: %w", ctx.Err())
+		case <-time.After(wait):
 		}
 	}
 	return fmt.Errorf("all %d attempts failed: %w", attempts, lastErr)
 }
 
-// PollUntil polls condition every interval until timeout is reached.
-// condition should return (true, nil) when the desired state is reached.
-func PollUntil(timeout, interval time.Duration, condition func() (bool, error)) error {
+// fullJitterBackoff returns a random duration in [0, min(backoffCap, backoffBase*2^attempt)],
+// the "full jitter" strategy that spreads out retries to avoid thundering-herd
+// retries against a throttled API.
+func fullJitterBackoff(attempt int) time.Duration {
+	maxWait := backoffBase * time.Duration(1<<uint(attempt))
+	if maxWait > backoffCap {
+		maxWait = backoffCap
+	}
+	return time.Duration(rand.Int63n(int64(maxWait) + 1))
+}
+
+// PollUntil polls condition every interval until timeout is reached, or
+// returns early if ctx is cancelled. condition should return (true, nil)
+// when the desired state is reached.
+func PollUntil(ctx context.Context, timeout, interval time.Duration, condition func() (bool, error)) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
 		done, err := condition()
@@ -37,7 +74,12 @@ func PollUntil(timeout, interval time.Duration, condition func() (bool, error))
 		if done {
 			return nil
 		}
-		time.Sleep(interval)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("poll aborted: %w", ctx.Err())
+		case <-time.After(interval):
+		}
 	}
 	return fmt.Errorf("timed out after %s — condition not met", timeout)
 }